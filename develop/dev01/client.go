@@ -0,0 +1,175 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/beevik/ntp"
+	"golang.org/x/net/ipv4"
+)
+
+// maxResponseLen - размер буфера для ответа сервера вместе с полями расширения.
+const maxResponseLen = 2048
+
+// Ошибки проверки ответа сервера.
+var (
+	ErrResponseMode     = errors.New("invalid mode in response")
+	ErrResponseMismatch = errors.New("server response mismatch")
+)
+
+// queryFunc - функция опроса одного сервера. Позволяет режимам работы не зависеть
+// от способа запроса: обычный NTP, NTS или запрос с симметричным ключом.
+type queryFunc func(server string) sample
+
+// plainQuery - обычный запрос через библиотеку ntp с параметрами opt.
+func plainQuery(opt ntp.QueryOptions) queryFunc {
+	return func(server string) sample {
+		return querySample(server, opt)
+	}
+}
+
+// clientRequest - заголовок запроса клиента со случайным временем отправки, как в библиотеке ntp:
+// сервер лишь копирует его в поле origin, а случайное значение защищает от подделки ответа.
+func clientRequest(version int) (*packet, error) {
+	bits := make([]byte, 8)
+	if _, err := rand.Read(bits); err != nil {
+		return nil, err
+	}
+	return &packet{
+		leap:         ntp.LeapNotInSync,
+		version:      uint8(version),
+		mode:         modeClient,
+		transmitTime: binary.BigEndian.Uint64(bits),
+	}, nil
+}
+
+// exchange - отправляет запрос req на host и возвращает ответ сервера, а также моменты
+// отправки и получения по локальным часам. Учитываются таймаут, порт, локальный адрес и TTL из opt.
+func exchange(host string, opt ntp.QueryOptions, req []byte) ([]byte, time.Time, time.Time, error) {
+	port := opt.Port
+	if port == 0 {
+		port = 123
+	}
+	raddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	var laddr *net.UDPAddr
+	if opt.LocalAddress != "" {
+		laddr, err = net.ResolveUDPAddr("udp", net.JoinHostPort(opt.LocalAddress, "0"))
+		if err != nil {
+			return nil, time.Time{}, time.Time{}, err
+		}
+	}
+
+	conn, err := net.DialUDP("udp", laddr, raddr)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	defer conn.Close()
+	if opt.TTL != 0 {
+		if err := ipv4.NewConn(conn).SetTTL(opt.TTL); err != nil {
+			return nil, time.Time{}, time.Time{}, err
+		}
+	}
+	timeout := opt.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	xmit := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	buf := make([]byte, maxResponseLen)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	recv := time.Now()
+	return buf[:n], xmit, recv, nil
+}
+
+// checkResponse - проверяет, что p - ответ сервера на запрос req.
+func checkResponse(req, p *packet) error {
+	if p.mode != modeServer {
+		return ErrResponseMode
+	}
+	if p.originTime != req.transmitTime {
+		return ErrResponseMismatch
+	}
+	if p.transmitTime == 0 {
+		return errors.New("invalid transmit time in response")
+	}
+	if p.receiveTime > p.transmitTime {
+		return errors.New("server clock ticked backwards")
+	}
+	return nil
+}
+
+// newResponse - вычисляет параметры ответа так же, как библиотека ntp.
+// org и dst - моменты отправки запроса и получения ответа по локальным часам.
+func newResponse(p *packet, org, dst time.Time) *ntp.Response {
+	rec := fromNtpTime(p.receiveTime)
+	xmt := fromNtpTime(p.transmitTime)
+	r := &ntp.Response{
+		Time:           xmt,
+		ClockOffset:    (rec.Sub(org) + xmt.Sub(dst)) / 2,
+		RTT:            dst.Sub(org) - xmt.Sub(rec),
+		Precision:      toInterval(p.precision),
+		Stratum:        p.stratum,
+		ReferenceID:    p.referenceID,
+		ReferenceTime:  fromNtpTime(p.referenceTime),
+		RootDelay:      fromNtpShort(p.rootDelay),
+		RootDispersion: fromNtpShort(p.rootDispersion),
+		Leap:           p.leap,
+		Poll:           toInterval(p.poll),
+	}
+	if r.RTT < 0 {
+		r.RTT = 0
+	}
+	var err0, err1 time.Duration
+	if org.After(rec) {
+		err0 = org.Sub(rec)
+	}
+	if xmt.After(dst) {
+		err1 = xmt.Sub(dst)
+	}
+	r.MinError = err0
+	if err1 > err0 {
+		r.MinError = err1
+	}
+	r.RootDistance = (r.RTT+r.RootDelay)/2 + r.RootDispersion
+	if r.Stratum == 0 {
+		r.KissCode = kissCode(r.ReferenceID)
+	}
+	return r
+}
+
+// toInterval - переводит степень двойки в секундах (поля poll и precision) в длительность.
+func toInterval(t int8) time.Duration {
+	switch {
+	case t > 0:
+		return time.Duration(uint64(time.Second) << uint(t))
+	case t < 0:
+		return time.Duration(uint64(time.Second) >> uint(-t))
+	default:
+		return time.Second
+	}
+}
+
+// kissCode - код kiss of death из идентификатора источника; пустая строка, если код непечатный.
+func kissCode(id uint32) string {
+	b := []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	for _, ch := range b {
+		if ch < 32 || ch > 126 {
+			return ""
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// ErrNoConsensus - ошибка, возвращаемая, когда большинство серверов не согласны между собой.
+var ErrNoConsensus = errors.New("no majority of servers agree on the time")
+
+// sample - результат опроса одного NTP-сервера.
+type sample struct {
+	server string
+	resp   *ntp.Response
+	err    error
+}
+
+// bounds - доверительный интервал смещения часов: смещение ± расстояние до корневого сервера.
+func (s sample) bounds() (time.Duration, time.Duration) {
+	return s.resp.ClockOffset - s.resp.RootDistance, s.resp.ClockOffset + s.resp.RootDistance
+}
+
+// consensus - результат выбора согласованного времени по нескольким серверам.
+type consensus struct {
+	offset       time.Duration // итоговое смещение локальных часов
+	low, high    time.Duration // пересечение интервалов согласных серверов
+	truechimers  []sample      // серверы, попавшие в пересечение
+	falsetickers []sample      // серверы, отброшенные как неверные
+	failed       []sample      // серверы, которые не ответили или вернули некорректный ответ
+}
+
+// splitList - разбивает список через запятую, отбрасывая пустые элементы и пробелы.
+func splitList(s string) []string {
+	ret := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// serverAddr - разбирает адрес вида host[:port]. Если порт не указан, возвращается 0.
+func serverAddr(addr string) (string, int, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// Адрес без порта, в том числе IPv6 без квадратных скобок.
+		return strings.Trim(addr, "[]"), 0, nil
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p <= 0 || p > 65535 {
+		return "", 0, fmt.Errorf("invalid port in server address %q", addr)
+	}
+	return host, p, nil
+}
+
+// queryAll - параллельно опрашивает все серверы. Порядок результатов совпадает с порядком серверов.
+func queryAll(servers []string, query queryFunc) []sample {
+	samples := make([]sample, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			samples[i] = query(server)
+		}(i, server)
+	}
+	wg.Wait()
+	return samples
+}
+
+// querySample - опрашивает один сервер и проверяет пригодность ответа для синхронизации.
+func querySample(server string, opt ntp.QueryOptions) sample {
+	s := sample{server: server}
+	host, port, err := serverAddr(server)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	if port != 0 {
+		opt.Port = port
+	}
+	s.resp, s.err = ntp.QueryWithOptions(host, opt)
+	if s.err == nil {
+		s.err = s.resp.Validate()
+	}
+	return s
+}
+
+// selectConsensus - отбирает серверы, показывающие согласованное время, по алгоритму пересечения
+// интервалов (алгоритм Марзулло в варианте RFC 5905, раздел 11.2.1).
+// Ищется наименьшее число f неверных серверов, при котором пересечение интервалов остальных n-f
+// непусто. Серверы, интервал которых не пересекается с найденным, считаются неверными.
+// Если согласие возможно только при f >= n/2, возвращается ErrNoConsensus.
+func selectConsensus(samples []sample) (*consensus, error) {
+	c := &consensus{}
+	valid := make([]sample, 0, len(samples))
+	for _, s := range samples {
+		if s.err != nil {
+			c.failed = append(c.failed, s)
+			continue
+		}
+		valid = append(valid, s)
+	}
+	n := len(valid)
+	if n == 0 {
+		return c, ErrNoConsensus
+	}
+
+	// Концы интервалов: -1 - начало, +1 - конец. При равных значениях начало идёт первым,
+	// чтобы соприкасающиеся интервалы считались пересекающимися.
+	type edge struct {
+		val time.Duration
+		typ int
+	}
+	edges := make([]edge, 0, 2*n)
+	for _, s := range valid {
+		lo, hi := s.bounds()
+		edges = append(edges, edge{lo, -1}, edge{hi, +1})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].val != edges[j].val {
+			return edges[i].val < edges[j].val
+		}
+		return edges[i].typ < edges[j].typ
+	})
+
+	found := false
+	for f := 0; 2*f < n; f++ {
+		lowOK, highOK := false, false
+		chime := 0
+		for _, e := range edges {
+			chime -= e.typ
+			if chime >= n-f {
+				c.low, lowOK = e.val, true
+				break
+			}
+		}
+		chime = 0
+		for i := len(edges) - 1; i >= 0; i-- {
+			chime += edges[i].typ
+			if chime >= n-f {
+				c.high, highOK = edges[i].val, true
+				break
+			}
+		}
+		if lowOK && highOK && c.low <= c.high {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.falsetickers = valid
+		return c, ErrNoConsensus
+	}
+
+	// Итоговое смещение - среднее смещений согласных серверов, взвешенное
+	// обратно пропорционально их расстоянию до корневого сервера.
+	var sum, weights float64
+	for _, s := range valid {
+		if lo, hi := s.bounds(); hi < c.low || lo > c.high {
+			c.falsetickers = append(c.falsetickers, s)
+			continue
+		}
+		c.truechimers = append(c.truechimers, s)
+		dist := s.resp.RootDistance
+		if dist < time.Microsecond {
+			dist = time.Microsecond
+		}
+		w := 1 / dist.Seconds()
+		sum += w * float64(s.resp.ClockOffset)
+		weights += w
+	}
+	c.offset = time.Duration(sum / weights)
+	return c, nil
+}
+
+// runConsensus - режим опроса нескольких серверов с выбором согласованного времени. Время
+// выводится в формате out; сведения о выборе серверов в формате по умолчанию идут в stdout,
+// а в остальных форматах - в stderr, чтобы stdout содержал только время.
+func runConsensus(servers []string, query queryFunc, out *outputFormat, stdout, stderr io.Writer) int {
+	c, err := selectConsensus(queryAll(servers, query))
+	for _, s := range c.failed {
+		fmt.Fprintf(stderr, "%s: %v\n", s.server, s.err)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return exitError
+	}
+
+	if err := out.write(stdout, time.Now().Add(c.offset), c.offset); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return exitError
+	}
+	details := stdout
+	if !out.plain() {
+		details = stderr
+	}
+	fmt.Fprintf(details, "offset: %v (interval %v .. %v)\n", c.offset, c.low, c.high)
+	fmt.Fprintf(details, "agreed: %s\n", strings.Join(sampleServers(c.truechimers), ", "))
+	if len(c.falsetickers) > 0 {
+		fmt.Fprintf(details, "rejected: %s\n", strings.Join(sampleServers(c.falsetickers), ", "))
+	}
+	return exitOK
+}
+
+// sampleServers - возвращает адреса серверов из списка результатов.
+func sampleServers(samples []sample) []string {
+	ret := make([]string, 0, len(samples))
+	for _, s := range samples {
+		ret = append(ret, s.server)
+	}
+	return ret
+}
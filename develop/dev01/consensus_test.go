@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/ntp"
+	"github.com/stretchr/testify/require"
+)
+
+var errFake = errors.New("fake query error")
+
+// fakeSample - результат опроса с заданным смещением и расстоянием до корневого сервера.
+func fakeSample(server string, offset, dist time.Duration) sample {
+	return sample{server: server, resp: &ntp.Response{ClockOffset: offset, RootDistance: dist}}
+}
+
+type selectTest struct {
+	name         string
+	samples      []sample
+	truechimers  []string
+	falsetickers []string
+	err          error
+}
+
+var selectTests = []selectTest{
+	{
+		name: "test1",
+		samples: []sample{
+			fakeSample("a", 10*time.Millisecond, 20*time.Millisecond),
+			fakeSample("b", 15*time.Millisecond, 20*time.Millisecond),
+			fakeSample("c", 5*time.Second, 20*time.Millisecond),
+		},
+		truechimers:  []string{"a", "b"},
+		falsetickers: []string{"c"},
+	},
+	{
+		name: "test2",
+		samples: []sample{
+			fakeSample("a", 0, 10*time.Millisecond),
+			fakeSample("b", 2*time.Second, 10*time.Millisecond),
+		},
+		err: ErrNoConsensus,
+	},
+	{
+		name: "test3",
+		samples: []sample{
+			fakeSample("a", 0, 10*time.Millisecond),
+			fakeSample("b", 20*time.Millisecond, 10*time.Millisecond),
+			fakeSample("c", -3*time.Second, 10*time.Millisecond),
+			{server: "d", err: errFake},
+		},
+		truechimers:  []string{"a", "b"},
+		falsetickers: []string{"c"},
+	},
+	{
+		name:    "test4",
+		samples: []sample{{server: "a", err: errFake}},
+		err:     ErrNoConsensus,
+	},
+}
+
+func TestSelectConsensus(t *testing.T) {
+	for _, test := range selectTests {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := selectConsensus(test.samples)
+			require.Equal(t, test.err, err)
+			if err != nil {
+				return
+			}
+			require.Equal(t, test.truechimers, sampleServers(c.truechimers))
+			require.Equal(t, test.falsetickers, sampleServers(c.falsetickers))
+			require.Len(t, c.truechimers, len(test.truechimers))
+		})
+	}
+}
+
+func TestRunConsensus(t *testing.T) {
+	good1 := testServer(t, 0)
+	good2 := testServer(t, 0)
+	good3 := testServer(t, 0)
+	bad1 := testServer(t, 5*time.Second)
+	bad2 := testServer(t, -3*time.Second)
+
+	var stdout, stderr bytes.Buffer
+	servers := strings.Join([]string{good1, bad1, good2, bad2, good3}, ",")
+	code := run([]string{"-servers", servers}, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Contains(t, stdout.String(), "agreed: "+strings.Join([]string{good1, good2, good3}, ", "))
+	require.Contains(t, stdout.String(), "rejected: "+bad1+", "+bad2)
+
+	// Время выводится в выбранном формате, сведения о серверах - в stderr.
+	stdout.Reset()
+	stderr.Reset()
+	code = run([]string{"-servers", servers, "-format", "unix"}, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	unix, err := strconv.ParseInt(strings.TrimSpace(stdout.String()), 10, 64)
+	require.NoError(t, err)
+	require.InDelta(t, time.Now().Unix(), unix, 2)
+	require.Contains(t, stderr.String(), "agreed: ")
+
+	stdout.Reset()
+	stderr.Reset()
+	code = run([]string{"-servers", servers, "-layout", "2006", "-tz", "UTC"}, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Equal(t, time.Now().UTC().Format("2006")+"\n", stdout.String())
+
+	stdout.Reset()
+	stderr.Reset()
+	code = run([]string{"-servers", good1 + "," + bad1}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), ErrNoConsensus.Error())
+
+	// Режимы без вывода точного времени не принимают параметры его вывода.
+	for _, args := range [][]string{
+		{"-report", "-host", good1, "-format", "unix"},
+		{"-monitor", "-host", good1, "-tz", "UTC"},
+		{"-serve", "127.0.0.1:0", "-layout", "2006"},
+	} {
+		stderr.Reset()
+		code = run(args, &stdout, &stderr)
+		require.Equal(t, exitUsage, code, args)
+		require.Contains(t, stderr.String(), "cannot be combined", args)
+	}
+}
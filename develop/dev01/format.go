@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Форматы вывода точного времени.
+const (
+	formatDefault     = "default"
+	formatRFC3339Nano = "rfc3339nano"
+	formatUnix        = "unix"
+	formatUnixNano    = "unixnano"
+	formatJSON        = "json"
+)
+
+// outputFormat - способ вывода точного времени: формат, пользовательский шаблон Go и часовой пояс.
+type outputFormat struct {
+	name   string
+	layout string
+	loc    *time.Location
+}
+
+// timeJSON - представление точного времени в формате JSON.
+type timeJSON struct {
+	Time     string  `json:"time"`
+	Unix     int64   `json:"unix"`
+	UnixNano int64   `json:"unix_nano"`
+	Zone     string  `json:"zone"`
+	Offset   float64 `json:"clock_offset"`
+}
+
+// newOutputFormat - проверяет параметры вывода. layout задаёт шаблон в нотации пакета time
+// и не сочетается с другими форматами; tz - название часового пояса IANA, пустая строка - локальный.
+func newOutputFormat(name, layout, tz string) (*outputFormat, error) {
+	f := &outputFormat{name: name, layout: layout, loc: time.Local}
+	switch name {
+	case formatDefault, formatRFC3339Nano, formatUnix, formatUnixNano, formatJSON:
+	default:
+		return nil, fmt.Errorf("unknown output format %q", name)
+	}
+	if layout != "" && name != formatDefault {
+		return nil, fmt.Errorf("-layout cannot be combined with -format %s", name)
+	}
+	if tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone %q", tz)
+		}
+		f.loc = loc
+	}
+	return f, nil
+}
+
+// plain - выводится ли время в формате по умолчанию (time.Time.String).
+func (f *outputFormat) plain() bool {
+	return f.name == formatDefault && f.layout == ""
+}
+
+// custom - заданы ли параметры вывода, отличные от значений по умолчанию.
+func (f *outputFormat) custom() bool {
+	return !f.plain() || f.loc != time.Local
+}
+
+// write - выводит время t, полученное с учётом смещения часов offset.
+func (f *outputFormat) write(w io.Writer, t time.Time, offset time.Duration) error {
+	t = t.In(f.loc)
+	if f.layout != "" {
+		_, err := fmt.Fprintln(w, t.Format(f.layout))
+		return err
+	}
+
+	var err error
+	switch f.name {
+	case formatRFC3339Nano:
+		_, err = fmt.Fprintln(w, t.Format(time.RFC3339Nano))
+	case formatUnix:
+		_, err = fmt.Fprintln(w, t.Unix())
+	case formatUnixNano:
+		_, err = fmt.Fprintln(w, t.UnixNano())
+	case formatJSON:
+		err = json.NewEncoder(w).Encode(timeJSON{
+			Time:     t.Format(time.RFC3339Nano),
+			Unix:     t.Unix(),
+			UnixNano: t.UnixNano(),
+			Zone:     f.loc.String(),
+			Offset:   offset.Seconds(),
+		})
+	default:
+		_, err = fmt.Fprintln(w, t.String())
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type formatTest struct {
+	name   string
+	format string
+	layout string
+	tz     string
+	exp    string
+}
+
+var formatTests = []formatTest{
+	{
+		name:   "test1",
+		format: formatRFC3339Nano,
+		tz:     "UTC",
+		exp:    "2022-12-01T09:30:15.123456789Z\n",
+	},
+	{
+		name:   "test2",
+		format: formatRFC3339Nano,
+		tz:     "Europe/Moscow",
+		exp:    "2022-12-01T12:30:15.123456789+03:00\n",
+	},
+	{
+		name:   "test3",
+		format: formatUnix,
+		exp:    "1669887015\n",
+	},
+	{
+		name:   "test4",
+		format: formatUnixNano,
+		exp:    "1669887015123456789\n",
+	},
+	{
+		name:   "test5",
+		format: formatDefault,
+		layout: "02.01.2006 15:04:05.000 MST",
+		tz:     "Asia/Tokyo",
+		exp:    "01.12.2022 18:30:15.123 JST\n",
+	},
+	{
+		name:   "test6",
+		format: formatDefault,
+		tz:     "UTC",
+		exp:    "2022-12-01 09:30:15.123456789 +0000 UTC\n",
+	},
+}
+
+func TestOutputFormat(t *testing.T) {
+	now := time.Date(2022, 12, 1, 9, 30, 15, 123456789, time.UTC)
+	for _, test := range formatTests {
+		t.Run(test.name, func(t *testing.T) {
+			f, err := newOutputFormat(test.format, test.layout, test.tz)
+			require.NoError(t, err)
+			var out bytes.Buffer
+			require.NoError(t, f.write(&out, now, 0))
+			require.Equal(t, test.exp, out.String())
+		})
+	}
+}
+
+func TestOutputFormatJSON(t *testing.T) {
+	f, err := newOutputFormat(formatJSON, "", "Europe/Moscow")
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, f.write(&out, time.Date(2022, 12, 1, 9, 30, 15, 0, time.UTC), 1500*time.Millisecond))
+
+	var res timeJSON
+	require.NoError(t, json.Unmarshal(out.Bytes(), &res))
+	require.Equal(t, timeJSON{
+		Time:     "2022-12-01T12:30:15+03:00",
+		Unix:     1669887015,
+		UnixNano: 1669887015000000000,
+		Zone:     "Europe/Moscow",
+		Offset:   1.5,
+	}, res)
+}
+
+func TestOutputFormatErrors(t *testing.T) {
+	_, err := newOutputFormat("iso", "", "")
+	require.EqualError(t, err, `unknown output format "iso"`)
+	_, err = newOutputFormat(formatUnix, "15:04", "")
+	require.EqualError(t, err, "-layout cannot be combined with -format unix")
+	_, err = newOutputFormat(formatDefault, "", "Mars/Olympus")
+	require.EqualError(t, err, `unknown time zone "Mars/Olympus"`)
+}
+
+func TestRunFormat(t *testing.T) {
+	server := testServer(t, 0)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-host", server, "-format", "unix"}, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	sec, err := strconv.ParseInt(strings.TrimSpace(stdout.String()), 10, 64)
+	require.NoError(t, err)
+	require.InDelta(t, time.Now().Unix(), sec, 2)
+
+	code = run([]string{"-host", server, "-tz", "Nowhere/City"}, &stdout, &stderr)
+	require.Equal(t, exitUsage, code)
+}
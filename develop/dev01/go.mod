@@ -2,10 +2,15 @@ module github.com/Ekspresso/l2-wb/develop/dev01
 
 go 1.18
 
-require github.com/beevik/ntp v0.3.0
+require (
+	github.com/beevik/ntp v0.3.0
+	github.com/stretchr/testify v1.8.1
+	golang.org/x/net v0.4.0
+)
 
 require (
-	github.com/stretchr/testify v1.8.1 // indirect
-	golang.org/x/net v0.4.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/sys v0.3.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
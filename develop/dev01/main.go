@@ -8,17 +8,166 @@ package main
 // Программа должна корректно обрабатывать ошибки библиотеки: выводить их в STDERR и возвращать ненулевой код выхода в OS
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// defaultHost - NTP-сервер, к которому программа обращается по умолчанию.
+const defaultHost = "0.beevik-ntp.pool.ntp.org"
 
-	"github.com/beevik/ntp"
+// Коды выхода программы.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+	exitAlert = 3
+	exitAuth  = 4
 )
 
 func main() {
-	time, err := ntp.Time("0.beevik-ntp.pool.ntp.org")
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run - разбирает аргументы командной строки и выполняет выбранный режим работы.
+// Ошибки выводятся в stderr, возвращаемое значение - код выхода для ОС.
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dev01", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	host := fs.String("host", defaultHost, "NTP-сервер для запроса")
+	servers := fs.String("servers", "", "список NTP-серверов через запятую (host[:port]) для выбора согласованного времени")
+	reportMode := fs.Bool("report", false, "вывести полную сводку ответа сервера")
+	asJSON := fs.Bool("json", false, "выводить сводку в формате JSON (вместе с -report)")
+	monitorMode := fs.Bool("monitor", false, "периодически опрашивать сервер и следить за расхождением часов")
+	interval := fs.Duration("interval", time.Minute, "интервал между опросами в режиме -monitor")
+	threshold := fs.Duration("threshold", 100*time.Millisecond, "допустимое смещение часов в режиме -monitor")
+	history := fs.Int("history", 60, "количество хранимых измерений в режиме -monitor")
+	count := fs.Int("count", 0, "количество опросов в режиме -monitor, 0 - без ограничения")
+	listen := fs.String("listen", "localhost:9123", "адрес HTTP-сервера с метриками /metrics, пустая строка - не запускать")
+	exitOnAlert := fs.Bool("exit-on-alert", false, "завершить -monitor при первом превышении порога")
+	serve := fs.String("serve", "", "запустить SNTP-сервер на указанном UDP-адресе, например :123")
+	skew := fs.Duration("skew", 0, "сдвиг часов SNTP-сервера относительно локальных")
+	fakeTime := fs.String("fake-time", "", "время в формате RFC3339, с которого начинают идти часы SNTP-сервера")
+	var client clientOptions
+	fs.DurationVar(&client.timeout, "timeout", 5*time.Second, "таймаут запроса к NTP-серверу")
+	fs.IntVar(&client.version, "version", 4, "версия протокола NTP: 2, 3 или 4")
+	fs.IntVar(&client.port, "port", 123, "порт NTP-сервера, если он не указан в адресе")
+	fs.StringVar(&client.local, "local", "", "локальный IP-адрес для отправки запросов")
+	fs.IntVar(&client.ttl, "ttl", 0, "TTL IP-пакетов запроса, 0 - системное значение")
+	nts := fs.Bool("nts", false, "запрашивать время с защитой NTS; порт в адресе сервера - порт NTS-KE (по умолчанию 4460)")
+	ntsCA := fs.String("nts-ca", "", "PEM-файл с корневыми сертификатами для проверки NTS-KE сервера")
+	keysFile := fs.String("keys", "", "файл симметричных ключей в формате ntp.keys")
+	keyID := fs.Uint("keyid", 0, "идентификатор ключа из -keys для подписи запросов")
+	format := fs.String("format", formatDefault, "формат вывода времени: default, rfc3339nano, unix, unixnano, json")
+	layout := fs.String("layout", "", "пользовательский шаблон вывода времени в нотации пакета time, например 2006-01-02 15:04:05.000")
+	tz := fs.String("tz", "", "часовой пояс IANA для вывода времени, например Europe/Moscow")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	opt, err := client.queryOptions()
 	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
+		fmt.Fprintln(stderr, err.Error())
+		return exitUsage
+	}
+	out, err := newOutputFormat(*format, *layout, *tz)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return exitUsage
+	}
+	query := plainQuery(opt)
+	if *nts {
+		tlsConfig, err := ntsTLSConfig(*ntsCA)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return exitUsage
+		}
+		query = ntsQuery(tlsConfig, opt)
+	}
+	var keys map[uint32]symKey
+	if *keysFile != "" {
+		if keys, err = loadKeys(*keysFile); err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return exitUsage
+		}
+	}
+	if *keyID != 0 {
+		k, ok := keys[uint32(*keyID)]
+		if !ok {
+			fmt.Fprintf(stderr, "key %d not found, check -keys\n", *keyID)
+			return exitUsage
+		}
+		if *nts {
+			fmt.Fprintln(stderr, "-keyid cannot be combined with -nts")
+			return exitUsage
+		}
+		query = authQuery(k, opt)
+	}
+
+	if out.custom() && (*serve != "" || *reportMode || *monitorMode) {
+		// Эти режимы не выводят точное время и не используют параметры его вывода.
+		fmt.Fprintln(stderr, "-format, -layout and -tz cannot be combined with -serve, -report or -monitor")
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch {
+	case *serve != "":
+		var start time.Time
+		if *fakeTime != "" {
+			start, err = time.Parse(time.RFC3339Nano, *fakeTime)
+			if err != nil {
+				fmt.Fprintln(stderr, err.Error())
+				return exitUsage
+			}
+		}
+		return runServe(ctx, *serve, skewedClock(start, *skew), keys, stderr)
+	case *servers != "":
+		return runConsensus(splitList(*servers), query, out, stdout, stderr)
+	case *reportMode:
+		return runReport(*host, query, *asJSON, stdout, stderr)
+	case *monitorMode:
+		return runMonitor(ctx, monitorConfig{
+			server:      *host,
+			query:       query,
+			interval:    *interval,
+			threshold:   *threshold,
+			history:     *history,
+			count:       *count,
+			listen:      *listen,
+			exitOnAlert: *exitOnAlert,
+		}, stdout, stderr)
+	case *asJSON:
+		fmt.Fprintln(stderr, "-json requires -report")
+		return exitUsage
+	}
+
+	// То же, что ntp.Time, но с поддержкой адреса вида host:port.
+	s := query(*host)
+	if s.err != nil {
+		fmt.Fprintln(stderr, s.err.Error())
+		return exitCode(s.err)
+	}
+	if err := out.write(stdout, time.Now().Add(s.resp.ClockOffset), s.resp.ClockOffset); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return exitError
+	}
+	return exitOK
+}
+
+// exitCode - код выхода для ошибки запроса: ошибки проверки подлинности ответа
+// отличаются от остальных, чтобы их можно было обработать отдельно.
+func exitCode(err error) int {
+	if errors.Is(err, ErrAuthFailed) || errors.Is(err, ErrNTSAuth) || errors.Is(err, ErrNTSNak) {
+		return exitAuth
 	}
-	fmt.Println(time.String())
+	return exitError
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// monitorConfig - параметры режима мониторинга расхождения часов.
+type monitorConfig struct {
+	server      string        // опрашиваемый сервер host[:port]
+	query       queryFunc     // способ опроса сервера
+	interval    time.Duration // интервал между опросами
+	threshold   time.Duration // допустимое по модулю смещение часов
+	history     int           // количество хранимых последних измерений
+	count       int           // количество опросов, 0 - без ограничения
+	listen      string        // адрес HTTP-сервера с метриками, пустая строка - не запускать
+	exitOnAlert bool          // завершить работу при первом превышении порога
+}
+
+// reading - одно измерение в истории мониторинга.
+type reading struct {
+	at      time.Time
+	offset  time.Duration
+	rtt     time.Duration
+	stratum uint8
+}
+
+// monitor - хранит историю измерений и счётчики, отдаёт их в формате Prometheus.
+type monitor struct {
+	server    string
+	threshold time.Duration
+	size      int
+
+	mu       sync.Mutex
+	history  []reading
+	polls    uint64
+	failures uint64
+	alerts   uint64
+}
+
+// newMonitor - создаёт монитор для сервера с историей из size измерений.
+func newMonitor(server string, threshold time.Duration, size int) *monitor {
+	if size < 1 {
+		size = 1
+	}
+	return &monitor{server: server, threshold: threshold, size: size}
+}
+
+// record - учитывает результат опроса. Возвращает true, если смещение превысило порог.
+func (m *monitor) record(s sample, at time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.polls++
+	if s.err != nil {
+		m.failures++
+		return false
+	}
+	m.history = append(m.history, reading{at: at, offset: s.resp.ClockOffset, rtt: s.resp.RTT, stratum: s.resp.Stratum})
+	if len(m.history) > m.size {
+		m.history = m.history[len(m.history)-m.size:]
+	}
+	if abs(s.resp.ClockOffset) > m.threshold {
+		m.alerts++
+		return true
+	}
+	return false
+}
+
+// alertCount - возвращает количество превышений порога.
+func (m *monitor) alertCount() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.alerts
+}
+
+// ServeHTTP - отдаёт метрики в текстовом формате Prometheus.
+func (m *monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.writeMetrics(w)
+}
+
+// writeMetrics - выводит метрики в текстовом формате Prometheus.
+func (m *monitor) writeMetrics(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	label := fmt.Sprintf("{server=%q}", m.server)
+	metric := func(name, typ, help string, value float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s%s %g\n", name, help, name, typ, name, label, value)
+	}
+
+	metric("ntp_polls_total", "counter", "Total number of NTP queries.", float64(m.polls))
+	metric("ntp_poll_failures_total", "counter", "Total number of failed or invalid NTP queries.", float64(m.failures))
+	metric("ntp_offset_alerts_total", "counter", "Total number of readings with clock offset above the threshold.", float64(m.alerts))
+	metric("ntp_offset_threshold_seconds", "gauge", "Configured clock offset alert threshold.", m.threshold.Seconds())
+	metric("ntp_history_readings", "gauge", "Number of readings in the rolling history.", float64(len(m.history)))
+	if len(m.history) == 0 {
+		return
+	}
+
+	last := m.history[len(m.history)-1]
+	var sumOffset, sumRTT, maxOffset time.Duration
+	for _, r := range m.history {
+		sumOffset += r.offset
+		sumRTT += r.rtt
+		if abs(r.offset) > maxOffset {
+			maxOffset = abs(r.offset)
+		}
+	}
+	n := time.Duration(len(m.history))
+
+	metric("ntp_clock_offset_seconds", "gauge", "Last measured clock offset.", last.offset.Seconds())
+	metric("ntp_rtt_seconds", "gauge", "Last measured round-trip delay.", last.rtt.Seconds())
+	metric("ntp_stratum", "gauge", "Last reported server stratum.", float64(last.stratum))
+	metric("ntp_last_reading_timestamp_seconds", "gauge", "Unix time of the last successful reading.", float64(last.at.UnixNano())/1e9)
+	metric("ntp_clock_offset_mean_seconds", "gauge", "Mean clock offset over the rolling history.", (sumOffset / n).Seconds())
+	metric("ntp_clock_offset_max_abs_seconds", "gauge", "Maximum absolute clock offset over the rolling history.", maxOffset.Seconds())
+	metric("ntp_rtt_mean_seconds", "gauge", "Mean round-trip delay over the rolling history.", (sumRTT / n).Seconds())
+}
+
+// abs - модуль длительности.
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// runMonitor - режим мониторинга: опрашивает сервер с заданным интервалом, пока не будет отменён
+// контекст или не исчерпан лимит опросов. Превышение порога выводится в stderr; при exitOnAlert
+// программа сразу завершается с кодом exitAlert, иначе этот код возвращается по окончании работы,
+// если превышения были.
+func runMonitor(ctx context.Context, cfg monitorConfig, stdout, stderr io.Writer) int {
+	if cfg.interval <= 0 {
+		fmt.Fprintln(stderr, "monitor interval must be positive")
+		return exitUsage
+	}
+	m := newMonitor(cfg.server, cfg.threshold, cfg.history)
+
+	if cfg.listen != "" {
+		listener, err := net.Listen("tcp", cfg.listen)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return exitError
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m)
+		srv := &http.Server{Handler: mux}
+		go srv.Serve(listener)
+		defer srv.Close()
+		fmt.Fprintf(stderr, "serving metrics on http://%s/metrics\n", listener.Addr())
+	}
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+	for i := 0; cfg.count == 0 || i < cfg.count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return monitorExit(m)
+			case <-ticker.C:
+			}
+		}
+
+		s := cfg.query(cfg.server)
+		now := time.Now()
+		alert := m.record(s, now)
+		if s.err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", cfg.server, s.err)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s offset=%v rtt=%v stratum=%d\n",
+			now.Format(time.RFC3339), s.resp.ClockOffset, s.resp.RTT, s.resp.Stratum)
+		if alert {
+			fmt.Fprintf(stderr, "ALERT: %s clock offset %v exceeds threshold %v\n", cfg.server, s.resp.ClockOffset, cfg.threshold)
+			if cfg.exitOnAlert {
+				return exitAlert
+			}
+		}
+	}
+	return monitorExit(m)
+}
+
+// monitorExit - код выхода по окончании мониторинга.
+func monitorExit(m *monitor) int {
+	if m.alertCount() > 0 {
+		return exitAlert
+	}
+	return exitOK
+}
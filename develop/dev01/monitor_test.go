@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/beevik/ntp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitorRecord(t *testing.T) {
+	m := newMonitor("a", 100*time.Millisecond, 2)
+	now := time.Now()
+	require.False(t, m.record(sample{resp: &ntp.Response{ClockOffset: 10 * time.Millisecond}}, now))
+	require.True(t, m.record(sample{resp: &ntp.Response{ClockOffset: -200 * time.Millisecond}}, now))
+	require.False(t, m.record(sample{err: errFake}, now))
+	require.False(t, m.record(sample{resp: &ntp.Response{ClockOffset: 30 * time.Millisecond}}, now))
+	require.Len(t, m.history, 2)
+	require.Equal(t, -200*time.Millisecond, m.history[0].offset)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	require.Contains(t, body, "# TYPE ntp_polls_total counter\n")
+	require.Contains(t, body, `ntp_polls_total{server="a"} 4`+"\n")
+	require.Contains(t, body, `ntp_poll_failures_total{server="a"} 1`+"\n")
+	require.Contains(t, body, `ntp_offset_alerts_total{server="a"} 1`+"\n")
+	require.Contains(t, body, `ntp_clock_offset_seconds{server="a"} 0.03`+"\n")
+	require.Contains(t, body, `ntp_clock_offset_max_abs_seconds{server="a"} 0.2`+"\n")
+	require.Contains(t, body, `ntp_clock_offset_mean_seconds{server="a"} -0.085`+"\n")
+}
+
+func TestRunMonitor(t *testing.T) {
+	cfg := monitorConfig{
+		server:    testServer(t, 0),
+		query:     plainQuery(ntp.QueryOptions{}),
+		interval:  10 * time.Millisecond,
+		threshold: time.Second,
+		history:   10,
+		count:     3,
+	}
+	var stdout, stderr bytes.Buffer
+	code := runMonitor(context.Background(), cfg, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Equal(t, 3, bytes.Count(stdout.Bytes(), []byte("offset=")))
+
+	cfg.server = testServer(t, 5*time.Second)
+	stderr.Reset()
+	code = runMonitor(context.Background(), cfg, io.Discard, &stderr)
+	require.Equal(t, exitAlert, code)
+	require.Equal(t, 3, bytes.Count(stderr.Bytes(), []byte("ALERT: ")))
+
+	cfg.count = 0
+	cfg.exitOnAlert = true
+	stderr.Reset()
+	code = runMonitor(context.Background(), cfg, io.Discard, &stderr)
+	require.Equal(t, exitAlert, code)
+	require.Equal(t, 1, bytes.Count(stderr.Bytes(), []byte("ALERT: ")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cfg.server = testServer(t, 0)
+	cfg.exitOnAlert = false
+	code = runMonitor(ctx, cfg, io.Discard, io.Discard)
+	require.Equal(t, exitOK, code)
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// Параметры NTS (RFC 8915).
+const (
+	ntsKEPort         = 4460
+	ntsALPN           = "ntske/1"
+	ntsExporterLabel  = "EXPORTER-network-time-security"
+	ntsProtocolNTPv4  = 0
+	ntsAEADSIVCMAC256 = 15
+	ntsUniqueIDLen    = 32
+	ntsNonceLen       = 16
+)
+
+// Типы записей протокола NTS-KE (RFC 8915, раздел 4).
+const (
+	keEndOfMessage = 0
+	keNextProtocol = 1
+	keError        = 2
+	keWarning      = 3
+	keAEAD         = 4
+	keNewCookie    = 5
+	keServer       = 6
+	kePort         = 7
+)
+
+// keCritical - бит критичности в поле типа записи NTS-KE.
+const keCritical = 0x8000
+
+// Типы полей расширения NTP, используемых NTS (RFC 8915, раздел 5.7).
+const (
+	extUniqueID          = 0x0104
+	extCookie            = 0x0204
+	extCookiePlaceholder = 0x0304
+	extAuthenticator     = 0x0404
+)
+
+// Ошибки NTS.
+var (
+	ErrNTSKE        = errors.New("nts-ke: invalid server response")
+	ErrNTSNoCookies = errors.New("nts-ke: server returned no cookies")
+	ErrNTSAuth      = errors.New("nts: response authentication failed")
+	ErrNTSNak       = errors.New("nts: server rejected the cookie (NTSN)")
+)
+
+// keRecord - запись протокола NTS-KE.
+type keRecord struct {
+	critical bool
+	typ      uint16
+	body     []byte
+}
+
+// ntsSession - результат обмена ключами: ключи AEAD, cookies и адрес NTP-сервера.
+type ntsSession struct {
+	c2s, s2c []byte
+	cookies  [][]byte
+	server   string // пустая строка - тот же хост, что у NTS-KE
+	port     int    // 0 - порт не согласован
+}
+
+// extField - поле расширения NTP; start - смещение начала поля в пакете.
+type extField struct {
+	typ   uint16
+	body  []byte
+	start int
+}
+
+// ntsTLSConfig - конфигурация TLS для NTS-KE. Если caFile не пуст, серверный сертификат
+// проверяется по корневым сертификатам из этого PEM-файла, иначе - по системным.
+func ntsTLSConfig(caFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS13, NextProtos: []string{ntsALPN}}
+	if caFile == "" {
+		return cfg, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RootCAs = x509.NewCertPool()
+	if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return cfg, nil
+}
+
+// ntsQuery - запрос времени с NTS. Порт в адресе сервера - порт NTS-KE (по умолчанию 4460),
+// адрес и порт NTP-сервера согласуются при обмене ключами.
+func ntsQuery(tlsConfig *tls.Config, opt ntp.QueryOptions) queryFunc {
+	return func(server string) sample {
+		s := sample{server: server}
+		s.resp, s.err = queryNTS(server, tlsConfig, opt)
+		if s.err == nil {
+			s.err = s.resp.Validate()
+		}
+		return s
+	}
+}
+
+// queryNTS - выполняет обмен ключами NTS-KE и один защищённый NTPv4-запрос.
+func queryNTS(server string, tlsConfig *tls.Config, opt ntp.QueryOptions) (*ntp.Response, error) {
+	host, port, err := serverAddr(server)
+	if err != nil {
+		return nil, err
+	}
+	if port == 0 {
+		port = ntsKEPort
+	}
+	sess, err := ntsKeyExchange(net.JoinHostPort(host, strconv.Itoa(port)), host, tlsConfig, opt.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if sess.server != "" {
+		host = sess.server
+	}
+	if sess.port != 0 {
+		opt.Port = sess.port
+	}
+
+	c2s, err := newSIV(sess.c2s)
+	if err != nil {
+		return nil, err
+	}
+	s2c, err := newSIV(sess.s2c)
+	if err != nil {
+		return nil, err
+	}
+	req, err := clientRequest(4)
+	if err != nil {
+		return nil, err
+	}
+	uid := make([]byte, ntsUniqueIDLen)
+	nonce := make([]byte, ntsNonceLen)
+	if _, err := rand.Read(uid); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	b := req.marshal()
+	b = appendExtField(b, extUniqueID, uid)
+	b = appendExtField(b, extCookie, sess.cookies[0])
+	b = appendExtField(b, extAuthenticator, authenticatorBody(nonce, c2s.seal(nonce, nil, b)))
+
+	raw, xmit, recv, err := exchange(host, opt, b)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parsePacket(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(req, p); err != nil {
+		return nil, err
+	}
+	if _, err := verifyNTS(raw, uid, s2c); err != nil {
+		if p.stratum == 0 && kissCode(p.referenceID) == "NTSN" {
+			return nil, ErrNTSNak
+		}
+		return nil, err
+	}
+	return newResponse(p, xmit, recv), nil
+}
+
+// ntsKeyExchange - выполняет NTS-KE по TLS 1.3 с сервером addr и экспортирует ключи AEAD.
+func ntsKeyExchange(addr, serverName string, tlsConfig *tls.Config, timeout time.Duration) (*ntsSession, error) {
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	cfg := tlsConfig.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = serverName
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	state := conn.ConnectionState()
+	if state.NegotiatedProtocol != ntsALPN {
+		return nil, fmt.Errorf("nts-ke: server did not negotiate %s", ntsALPN)
+	}
+
+	var req []byte
+	req = appendKERecord(req, true, keNextProtocol, uint16s(ntsProtocolNTPv4))
+	req = appendKERecord(req, false, keAEAD, uint16s(ntsAEADSIVCMAC256))
+	req = appendKERecord(req, true, keEndOfMessage, nil)
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	records, err := readKERecords(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	sess := &ntsSession{}
+	protoOK, aeadOK := false, false
+	for _, r := range records {
+		switch r.typ {
+		case keNextProtocol:
+			protoOK = bytes.Equal(r.body, uint16s(ntsProtocolNTPv4))
+		case keAEAD:
+			aeadOK = bytes.Equal(r.body, uint16s(ntsAEADSIVCMAC256))
+		case keError:
+			if len(r.body) != 2 {
+				return nil, ErrNTSKE
+			}
+			return nil, fmt.Errorf("nts-ke: server error %d", binary.BigEndian.Uint16(r.body))
+		case keNewCookie:
+			sess.cookies = append(sess.cookies, r.body)
+		case keServer:
+			sess.server = string(r.body)
+		case kePort:
+			if len(r.body) != 2 {
+				return nil, ErrNTSKE
+			}
+			sess.port = int(binary.BigEndian.Uint16(r.body))
+		case keWarning:
+		default:
+			if r.critical {
+				return nil, fmt.Errorf("nts-ke: unknown critical record type %d", r.typ)
+			}
+		}
+	}
+	if !protoOK || !aeadOK {
+		return nil, ErrNTSKE
+	}
+	if len(sess.cookies) == 0 {
+		return nil, ErrNTSNoCookies
+	}
+
+	sess.c2s, sess.s2c, err = ntsExportKeys(state)
+	if err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// ntsExportKeys - экспортирует ключи клиент-сервер и сервер-клиент из сессии TLS (RFC 8915, раздел 5.1).
+func ntsExportKeys(state tls.ConnectionState) ([]byte, []byte, error) {
+	context := func(direction byte) []byte {
+		return append(uint16s(ntsProtocolNTPv4, ntsAEADSIVCMAC256), direction)
+	}
+	c2s, err := state.ExportKeyingMaterial(ntsExporterLabel, context(0), sivKeyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	s2c, err := state.ExportKeyingMaterial(ntsExporterLabel, context(1), sivKeyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c2s, s2c, nil
+}
+
+// appendKERecord - добавляет запись NTS-KE к b.
+func appendKERecord(b []byte, critical bool, typ uint16, body []byte) []byte {
+	if critical {
+		typ |= keCritical
+	}
+	b = appendUint16(b, typ)
+	b = appendUint16(b, uint16(len(body)))
+	return append(b, body...)
+}
+
+// readKERecords - читает записи NTS-KE до записи End of Message включительно.
+func readKERecords(r io.Reader) ([]keRecord, error) {
+	records := make([]keRecord, 0)
+	hdr := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			return nil, err
+		}
+		typ := binary.BigEndian.Uint16(hdr)
+		body := make([]byte, binary.BigEndian.Uint16(hdr[2:]))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, err
+		}
+		rec := keRecord{critical: typ&keCritical != 0, typ: typ &^ keCritical, body: body}
+		if rec.typ == keEndOfMessage {
+			return records, nil
+		}
+		records = append(records, rec)
+	}
+}
+
+// uint16s - кодирует список 16-битных значений в сетевом порядке байт.
+func uint16s(vals ...uint16) []byte {
+	b := make([]byte, 0, 2*len(vals))
+	for _, v := range vals {
+		b = appendUint16(b, v)
+	}
+	return b
+}
+
+// appendExtField - добавляет к пакету поле расширения NTP, дополняя его до границы 4 байт
+// и минимального размера 16 байт (RFC 7822).
+func appendExtField(b []byte, typ uint16, body []byte) []byte {
+	n := 4 + len(body)
+	if n < 16 {
+		n = 16
+	}
+	n = (n + 3) &^ 3
+	b = appendUint16(b, typ)
+	b = appendUint16(b, uint16(n))
+	b = append(b, body...)
+	return append(b, make([]byte, n-4-len(body))...)
+}
+
+// parseExtFields - разбирает поля расширения, начиная со смещения off.
+func parseExtFields(b []byte, off int) ([]extField, error) {
+	fields := make([]extField, 0)
+	for off < len(b) {
+		if len(b)-off < 4 {
+			return nil, errors.New("truncated ntp extension field")
+		}
+		n := int(binary.BigEndian.Uint16(b[off+2:]))
+		if n < 4 || n%4 != 0 || off+n > len(b) {
+			return nil, errors.New("invalid ntp extension field length")
+		}
+		fields = append(fields, extField{typ: binary.BigEndian.Uint16(b[off:]), body: b[off+4 : off+n], start: off})
+		off += n
+	}
+	return fields, nil
+}
+
+// authenticatorBody - тело поля NTS Authenticator and Encrypted Extension Fields.
+func authenticatorBody(nonce, ciphertext []byte) []byte {
+	b := uint16s(uint16(len(nonce)), uint16(len(ciphertext)))
+	b = append(b, nonce...)
+	b = append(b, make([]byte, (4-len(nonce)%4)%4)...)
+	b = append(b, ciphertext...)
+	return append(b, make([]byte, (4-len(ciphertext)%4)%4)...)
+}
+
+// parseAuthenticator - извлекает nonce и шифротекст из поля NTS Authenticator.
+func parseAuthenticator(body []byte) ([]byte, []byte, error) {
+	if len(body) < 4 {
+		return nil, nil, ErrNTSAuth
+	}
+	nonceLen := int(binary.BigEndian.Uint16(body))
+	ctLen := int(binary.BigEndian.Uint16(body[2:]))
+	nonceEnd := 4 + (nonceLen+3)&^3
+	if nonceEnd+ctLen > len(body) {
+		return nil, nil, ErrNTSAuth
+	}
+	return body[4 : 4+nonceLen], body[nonceEnd : nonceEnd+ctLen], nil
+}
+
+// verifyNTS - проверяет поля NTS в пакете raw: совпадение Unique Identifier и подлинность
+// по полю Authenticator, которое должно быть последним. Возвращает зашифрованные поля расширения.
+func verifyNTS(raw, uid []byte, aead *siv) ([]extField, error) {
+	fields, err := parseExtFields(raw, packetLen)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 || fields[len(fields)-1].typ != extAuthenticator {
+		return nil, ErrNTSAuth
+	}
+	uidOK := false
+	for _, f := range fields {
+		if f.typ == extUniqueID && bytes.Equal(f.body, uid) {
+			uidOK = true
+		}
+	}
+	if !uidOK {
+		return nil, ErrResponseMismatch
+	}
+
+	auth := fields[len(fields)-1]
+	nonce, ciphertext, err := parseAuthenticator(auth.body)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.open(nonce, ciphertext, raw[:auth.start])
+	if err != nil {
+		return nil, ErrNTSAuth
+	}
+	return parseExtFields(plaintext, 0)
+}
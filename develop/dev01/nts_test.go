@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// ntsTestServer - локальная замена NTS-сервера: NTS-KE по TLS 1.3 и NTP-сервер,
+// проверяющий и подписывающий поля NTS. Cookie - ключи сессии, зашифрованные ключом master.
+type ntsTestServer struct {
+	keAddr string
+	caFile string
+	master *siv
+	ntp    *sntpServer
+
+	tamper        int32 // испортить подпись ответа (atomic)
+	rejectCookies int32 // отвечать NTSN на любой запрос (atomic)
+}
+
+// startNTSServer - запускает NTS-KE и NTP-сервер на localhost с часами, сдвинутыми на skew.
+func startNTSServer(t *testing.T, skew time.Duration) *ntsTestServer {
+	key := make([]byte, sivKeyLen)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	master, err := newSIV(key)
+	require.NoError(t, err)
+
+	ntpSrv, err := newSNTPServer("127.0.0.1:0", skewedClock(time.Time{}, skew))
+	require.NoError(t, err)
+	t.Cleanup(func() { ntpSrv.close() })
+
+	cert, caFile := testCertificate(t)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		NextProtos:   []string{ntsALPN},
+		Certificates: []tls.Certificate{cert},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	s := &ntsTestServer{keAddr: listener.Addr().String(), caFile: caFile, master: master, ntp: ntpSrv}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handleKE(conn.(*tls.Conn))
+		}
+	}()
+	go s.serveNTP()
+	return s
+}
+
+// testCertificate - самоподписанный сертификат для 127.0.0.1 и путь к его PEM-файлу.
+func testCertificate(t *testing.T) (tls.Certificate, string) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nts test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	require.NoError(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: priv}, caFile
+}
+
+// handleKE - обслуживает одно соединение NTS-KE.
+func (s *ntsTestServer) handleKE(conn *tls.Conn) {
+	defer conn.Close()
+	if err := conn.Handshake(); err != nil {
+		return
+	}
+	if _, err := readKERecords(conn); err != nil {
+		return
+	}
+	c2s, s2c, err := ntsExportKeys(conn.ConnectionState())
+	if err != nil {
+		return
+	}
+	port := uint16(s.ntp.addr().(*net.UDPAddr).Port)
+
+	var resp []byte
+	resp = appendKERecord(resp, true, keNextProtocol, uint16s(ntsProtocolNTPv4))
+	resp = appendKERecord(resp, true, keAEAD, uint16s(ntsAEADSIVCMAC256))
+	resp = appendKERecord(resp, true, kePort, uint16s(port))
+	for i := 0; i < 8; i++ {
+		resp = appendKERecord(resp, false, keNewCookie, s.cookie(c2s, s2c))
+	}
+	resp = appendKERecord(resp, true, keEndOfMessage, nil)
+	conn.Write(resp)
+}
+
+// cookie - шифрует ключи сессии ключом сервера.
+func (s *ntsTestServer) cookie(c2s, s2c []byte) []byte {
+	nonce := make([]byte, ntsNonceLen)
+	rand.Read(nonce)
+	return append(nonce, s.master.seal(nonce, append(append([]byte(nil), c2s...), s2c...))...)
+}
+
+// serveNTP - отвечает на NTP-запросы с полями NTS.
+func (s *ntsTestServer) serveNTP() {
+	buf := make([]byte, maxResponseLen)
+	for {
+		n, addr, err := s.ntp.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if resp := s.replyNTS(buf[:n], s.ntp.clock()); resp != nil {
+			s.ntp.conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+// replyNTS - проверяет запрос по ключу из cookie и формирует подписанный ответ.
+func (s *ntsTestServer) replyNTS(req []byte, recv time.Time) []byte {
+	resp := s.ntp.reply(req, recv)
+	fields, err := parseExtFields(req, packetLen)
+	if resp == nil || err != nil || len(fields) == 0 {
+		return nil
+	}
+	var uid, cookie []byte
+	for _, f := range fields {
+		switch f.typ {
+		case extUniqueID:
+			uid = f.body
+		case extCookie:
+			cookie = f.body
+		}
+	}
+
+	nak := func() []byte {
+		p, _ := parsePacket(resp)
+		p.stratum = 0
+		p.referenceID = 0x4e54534e // "NTSN"
+		return appendExtField(p.marshal(), extUniqueID, uid)
+	}
+	if atomic.LoadInt32(&s.rejectCookies) == 1 || len(cookie) < ntsNonceLen {
+		return nak()
+	}
+	keys, err := s.master.open(cookie[:ntsNonceLen], cookie[ntsNonceLen:])
+	if err != nil {
+		return nak()
+	}
+	c2s, _ := newSIV(keys[:sivKeyLen])
+	s2c, _ := newSIV(keys[sivKeyLen:])
+	auth := fields[len(fields)-1]
+	nonce, ciphertext, err := parseAuthenticator(auth.body)
+	if auth.typ != extAuthenticator || err != nil {
+		return nak()
+	}
+	if _, err := c2s.open(nonce, ciphertext, req[:auth.start]); err != nil {
+		return nak()
+	}
+
+	resp = appendExtField(resp, extUniqueID, uid)
+	respNonce := make([]byte, ntsNonceLen)
+	rand.Read(respNonce)
+	sealed := s2c.seal(respNonce, appendExtField(nil, extCookie, s.cookie(keys[:sivKeyLen], keys[sivKeyLen:])), resp)
+	if atomic.LoadInt32(&s.tamper) == 1 {
+		sealed[len(sealed)-1] ^= 1
+	}
+	return appendExtField(resp, extAuthenticator, authenticatorBody(respNonce, sealed))
+}
+
+func TestExtFields(t *testing.T) {
+	b := appendExtField(nil, extUniqueID, []byte{1, 2, 3})
+	require.Len(t, b, 16)
+	b = appendExtField(b, extCookie, bytes.Repeat([]byte{7}, 21))
+	require.Len(t, b, 16+28)
+
+	fields, err := parseExtFields(b, 0)
+	require.NoError(t, err)
+	require.Len(t, fields, 2)
+	require.Equal(t, uint16(extUniqueID), fields[0].typ)
+	require.Equal(t, []byte{1, 2, 3}, fields[0].body[:3])
+	require.Equal(t, 16, fields[1].start)
+
+	_, err = parseExtFields(b[:20], 0)
+	require.Error(t, err)
+
+	body := authenticatorBody([]byte{1, 2, 3, 4, 5}, []byte{9, 9})
+	nonce, ciphertext, err := parseAuthenticator(body)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3, 4, 5}, nonce)
+	require.Equal(t, []byte{9, 9}, ciphertext)
+}
+
+func TestKERecords(t *testing.T) {
+	var b []byte
+	b = appendKERecord(b, true, keNextProtocol, uint16s(ntsProtocolNTPv4))
+	b = appendKERecord(b, false, keNewCookie, []byte("cookie"))
+	b = appendKERecord(b, true, keEndOfMessage, nil)
+	require.Equal(t, []byte{0x80, 0x01, 0, 2, 0, 0}, b[:6])
+
+	records, err := readKERecords(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.Equal(t, []keRecord{
+		{critical: true, typ: keNextProtocol, body: []byte{0, 0}},
+		{critical: false, typ: keNewCookie, body: []byte("cookie")},
+	}, records)
+
+	_, err = readKERecords(bytes.NewReader(b[:len(b)-4]))
+	require.Error(t, err)
+}
+
+func TestRunNTS(t *testing.T) {
+	s := startNTSServer(t, time.Second)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-nts", "-nts-ca", s.caFile, "-report", "-json", "-host", s.keAddr}, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	var rep report
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &rep))
+	require.InDelta(t, 1, rep.ClockOffset, 0.5)
+	require.Equal(t, "LOCL", rep.ReferenceID)
+
+	// Без корневого сертификата тестового сервера TLS-рукопожатие не проходит.
+	stderr.Reset()
+	code = run([]string{"-nts", "-host", s.keAddr}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "certificate")
+}
+
+func TestQueryNTSErrors(t *testing.T) {
+	s := startNTSServer(t, 0)
+	tlsConfig, err := ntsTLSConfig(s.caFile)
+	require.NoError(t, err)
+	opt, err := clientOptions{timeout: time.Second, version: 4, port: 123}.queryOptions()
+	require.NoError(t, err)
+
+	_, err = queryNTS(s.keAddr, tlsConfig, opt)
+	require.NoError(t, err)
+
+	atomic.StoreInt32(&s.tamper, 1)
+	_, err = queryNTS(s.keAddr, tlsConfig, opt)
+	require.Equal(t, ErrNTSAuth, err)
+
+	atomic.StoreInt32(&s.tamper, 0)
+	atomic.StoreInt32(&s.rejectCookies, 1)
+	_, err = queryNTS(s.keAddr, tlsConfig, opt)
+	require.Equal(t, ErrNTSNak, err)
+
+	// Обычный NTP-сервер без NTS-KE.
+	_, err = queryNTS(testServer(t, 0), tlsConfig, opt)
+	require.Error(t, err)
+
+	_, err = ntsTLSConfig(filepath.Join(t.TempDir(), "missing.pem"))
+	require.Error(t, err)
+}
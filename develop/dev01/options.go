@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// clientOptions - параметры NTP-клиента, заданные флагами командной строки.
+type clientOptions struct {
+	timeout time.Duration
+	version int
+	port    int
+	local   string
+	ttl     int
+}
+
+// queryOptions - проверяет параметры клиента и переводит их в ntp.QueryOptions.
+// Порт, указанный в адресе сервера (host:port), имеет приоритет над port.
+func (o clientOptions) queryOptions() (ntp.QueryOptions, error) {
+	if o.timeout <= 0 {
+		return ntp.QueryOptions{}, fmt.Errorf("invalid timeout %v: must be positive", o.timeout)
+	}
+	if o.version < 2 || o.version > 4 {
+		return ntp.QueryOptions{}, fmt.Errorf("invalid NTP version %d: must be 2, 3 or 4", o.version)
+	}
+	if o.port < 1 || o.port > 65535 {
+		return ntp.QueryOptions{}, fmt.Errorf("invalid port %d", o.port)
+	}
+	if o.local != "" && net.ParseIP(o.local) == nil {
+		return ntp.QueryOptions{}, fmt.Errorf("invalid local address %q: must be an IP address", o.local)
+	}
+	if o.ttl < 0 || o.ttl > 255 {
+		return ntp.QueryOptions{}, fmt.Errorf("invalid TTL %d: must be in range 0..255", o.ttl)
+	}
+	return ntp.QueryOptions{
+		Timeout:      o.timeout,
+		Version:      o.version,
+		Port:         o.port,
+		LocalAddress: o.local,
+		TTL:          o.ttl,
+	}, nil
+}
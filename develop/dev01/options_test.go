@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type optionsTest struct {
+	name string
+	opt  clientOptions
+	err  string
+}
+
+var optionsTests = []optionsTest{
+	{
+		name: "test1",
+		opt:  clientOptions{timeout: time.Second, version: 4, port: 123},
+	},
+	{
+		name: "test2",
+		opt:  clientOptions{timeout: time.Second, version: 2, port: 1123, local: "127.0.0.1", ttl: 64},
+	},
+	{
+		name: "test3",
+		opt:  clientOptions{timeout: 0, version: 4, port: 123},
+		err:  "invalid timeout 0s: must be positive",
+	},
+	{
+		name: "test4",
+		opt:  clientOptions{timeout: time.Second, version: 5, port: 123},
+		err:  "invalid NTP version 5: must be 2, 3 or 4",
+	},
+	{
+		name: "test5",
+		opt:  clientOptions{timeout: time.Second, version: 4, port: 70000},
+		err:  "invalid port 70000",
+	},
+	{
+		name: "test6",
+		opt:  clientOptions{timeout: time.Second, version: 4, port: 123, local: "localhost"},
+		err:  `invalid local address "localhost": must be an IP address`,
+	},
+	{
+		name: "test7",
+		opt:  clientOptions{timeout: time.Second, version: 4, port: 123, ttl: 256},
+		err:  "invalid TTL 256: must be in range 0..255",
+	},
+}
+
+func TestQueryOptions(t *testing.T) {
+	for _, test := range optionsTests {
+		t.Run(test.name, func(t *testing.T) {
+			opt, err := test.opt.queryOptions()
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.opt.timeout, opt.Timeout)
+			require.Equal(t, test.opt.version, opt.Version)
+			require.Equal(t, test.opt.port, opt.Port)
+			require.Equal(t, test.opt.local, opt.LocalAddress)
+			require.Equal(t, test.opt.ttl, opt.TTL)
+		})
+	}
+}
+
+func TestRunClientOptions(t *testing.T) {
+	server := testServer(t, 0)
+	host, port, err := serverAddr(server)
+	require.NoError(t, err)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-host", host, "-port", strconv.Itoa(port), "-version", "3", "-local", "127.0.0.1", "-timeout", "1s"}, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+
+	stderr.Reset()
+	code = run([]string{"-host", server, "-version", "1"}, &stdout, &stderr)
+	require.Equal(t, exitUsage, code)
+	require.Equal(t, "invalid NTP version 1: must be 2, 3 or 4\n", stderr.String())
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// packetLen - длина заголовка NTP-пакета без полей расширения.
+const packetLen = 48
+
+// Режимы NTP-пакета.
+const (
+	modeClient = 3
+	modeServer = 4
+)
+
+// ntpEpoch - начало отсчёта времени NTP.
+var ntpEpoch = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ErrShortPacket - ошибка разбора пакета короче заголовка NTP.
+var ErrShortPacket = errors.New("ntp packet too short")
+
+// packet - заголовок NTP-пакета (RFC 5905, раздел 7.3). Времена хранятся в формате NTP:
+// 64-битные в Q32.32, корневые задержка и дисперсия - в Q16.16.
+type packet struct {
+	leap           ntp.LeapIndicator
+	version        uint8
+	mode           uint8
+	stratum        uint8
+	poll           int8
+	precision      int8
+	rootDelay      uint32
+	rootDispersion uint32
+	referenceID    uint32
+	referenceTime  uint64
+	originTime     uint64
+	receiveTime    uint64
+	transmitTime   uint64
+}
+
+// marshal - кодирует заголовок пакета в сетевом порядке байт.
+func (p *packet) marshal() []byte {
+	b := make([]byte, packetLen)
+	b[0] = uint8(p.leap)<<6 | (p.version&0x07)<<3 | p.mode&0x07
+	b[1] = p.stratum
+	b[2] = uint8(p.poll)
+	b[3] = uint8(p.precision)
+	binary.BigEndian.PutUint32(b[4:], p.rootDelay)
+	binary.BigEndian.PutUint32(b[8:], p.rootDispersion)
+	binary.BigEndian.PutUint32(b[12:], p.referenceID)
+	binary.BigEndian.PutUint64(b[16:], p.referenceTime)
+	binary.BigEndian.PutUint64(b[24:], p.originTime)
+	binary.BigEndian.PutUint64(b[32:], p.receiveTime)
+	binary.BigEndian.PutUint64(b[40:], p.transmitTime)
+	return b
+}
+
+// parsePacket - разбирает заголовок NTP-пакета. Поля расширения и MAC после заголовка игнорируются.
+func parsePacket(b []byte) (*packet, error) {
+	if len(b) < packetLen {
+		return nil, ErrShortPacket
+	}
+	return &packet{
+		leap:           ntp.LeapIndicator(b[0] >> 6),
+		version:        (b[0] >> 3) & 0x07,
+		mode:           b[0] & 0x07,
+		stratum:        b[1],
+		poll:           int8(b[2]),
+		precision:      int8(b[3]),
+		rootDelay:      binary.BigEndian.Uint32(b[4:]),
+		rootDispersion: binary.BigEndian.Uint32(b[8:]),
+		referenceID:    binary.BigEndian.Uint32(b[12:]),
+		referenceTime:  binary.BigEndian.Uint64(b[16:]),
+		originTime:     binary.BigEndian.Uint64(b[24:]),
+		receiveTime:    binary.BigEndian.Uint64(b[32:]),
+		transmitTime:   binary.BigEndian.Uint64(b[40:]),
+	}, nil
+}
+
+// toNtpTime - переводит время в 64-битный формат NTP.
+func toNtpTime(t time.Time) uint64 {
+	d := t.Sub(ntpEpoch)
+	sec := uint64(d / time.Second)
+	frac := uint64(d%time.Second) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
+
+// fromNtpTime - переводит 64-битное время NTP в time.Time.
+func fromNtpTime(v uint64) time.Time {
+	sec := time.Duration(v>>32) * time.Second
+	frac := time.Duration((v & 0xffffffff) * uint64(time.Second) >> 32)
+	return ntpEpoch.Add(sec + frac)
+}
+
+// toNtpShort - переводит длительность в 32-битный формат NTP (Q16.16).
+func toNtpShort(d time.Duration) uint32 {
+	return uint32(uint64(d) << 16 / uint64(time.Second))
+}
+
+// fromNtpShort - переводит 32-битное значение NTP (Q16.16) в длительность.
+func fromNtpShort(v uint32) time.Duration {
+	return time.Duration(uint64(v) * uint64(time.Second) >> 16)
+}
+
+// appendUint16 - добавляет к b 16-битное значение в сетевом порядке байт.
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// appendUint32 - добавляет к b 32-битное значение в сетевом порядке байт.
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
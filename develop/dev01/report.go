@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// report - сводка ответа NTP-сервера для диагностики расхождения часов.
+// Длительности в JSON передаются в секундах.
+type report struct {
+	Server          string    `json:"server"`
+	Time            time.Time `json:"time"`
+	ClockOffset     float64   `json:"clock_offset"`
+	RTT             float64   `json:"rtt"`
+	Stratum         uint8     `json:"stratum"`
+	ReferenceID     string    `json:"reference_id"`
+	ReferenceTime   time.Time `json:"reference_time"`
+	RootDelay       float64   `json:"root_delay"`
+	RootDispersion  float64   `json:"root_dispersion"`
+	RootDistance    float64   `json:"root_distance"`
+	Leap            string    `json:"leap"`
+	Precision       float64   `json:"precision"`
+	KissCode        string    `json:"kiss_code,omitempty"`
+	ValidationError string    `json:"validation_error,omitempty"`
+}
+
+// newReport - формирует сводку по ответу сервера и результату его проверки.
+func newReport(server string, r *ntp.Response, validateErr error) *report {
+	rep := &report{
+		Server:         server,
+		Time:           r.Time,
+		ClockOffset:    r.ClockOffset.Seconds(),
+		RTT:            r.RTT.Seconds(),
+		Stratum:        r.Stratum,
+		ReferenceID:    referenceID(r.Stratum, r.ReferenceID),
+		ReferenceTime:  r.ReferenceTime,
+		RootDelay:      r.RootDelay.Seconds(),
+		RootDispersion: r.RootDispersion.Seconds(),
+		RootDistance:   r.RootDistance.Seconds(),
+		Leap:           leapString(r.Leap),
+		Precision:      r.Precision.Seconds(),
+		KissCode:       r.KissCode,
+	}
+	if validateErr != nil {
+		rep.ValidationError = validateErr.Error()
+	}
+	return rep
+}
+
+// referenceID - представляет идентификатор источника времени в читаемом виде.
+// Для stratum 0 и 1 это четырёхсимвольный код (GPS, PPS, код kiss of death),
+// для остальных - IPv4-адрес вышестоящего сервера.
+func referenceID(stratum uint8, id uint32) string {
+	b := []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	if stratum <= 1 {
+		return strings.TrimRight(string(b), "\x00")
+	}
+	return net.IP(b).String()
+}
+
+// leapString - возвращает название индикатора дополнительной секунды.
+func leapString(leap ntp.LeapIndicator) string {
+	switch leap {
+	case ntp.LeapNoWarning:
+		return "none"
+	case ntp.LeapAddSecond:
+		return "add_second"
+	case ntp.LeapDelSecond:
+		return "del_second"
+	default:
+		return "not_in_sync"
+	}
+}
+
+// writeText - выводит сводку в виде таблицы "поле: значение".
+func (rep *report) writeText(w io.Writer) {
+	sec := func(f float64) time.Duration { return time.Duration(f * float64(time.Second)) }
+	fmt.Fprintf(w, "server:           %s\n", rep.Server)
+	fmt.Fprintf(w, "time:             %s\n", rep.Time.Format(time.RFC3339Nano))
+	fmt.Fprintf(w, "clock offset:     %v\n", sec(rep.ClockOffset))
+	fmt.Fprintf(w, "round-trip delay: %v\n", sec(rep.RTT))
+	fmt.Fprintf(w, "stratum:          %d\n", rep.Stratum)
+	fmt.Fprintf(w, "reference id:     %s\n", rep.ReferenceID)
+	fmt.Fprintf(w, "reference time:   %s\n", rep.ReferenceTime.Format(time.RFC3339Nano))
+	fmt.Fprintf(w, "root delay:       %v\n", sec(rep.RootDelay))
+	fmt.Fprintf(w, "root dispersion:  %v\n", sec(rep.RootDispersion))
+	fmt.Fprintf(w, "root distance:    %v\n", sec(rep.RootDistance))
+	fmt.Fprintf(w, "leap indicator:   %s\n", rep.Leap)
+	fmt.Fprintf(w, "precision:        %v\n", sec(rep.Precision))
+	if rep.KissCode != "" {
+		fmt.Fprintf(w, "kiss code:        %s\n", rep.KissCode)
+	}
+}
+
+// runReport - режим вывода полной сводки ответа сервера.
+// Сводка выводится и для ответа, не прошедшего проверку, но код выхода в этом случае ненулевой.
+func runReport(server string, query queryFunc, asJSON bool, stdout, stderr io.Writer) int {
+	s := query(server)
+	if s.resp == nil {
+		fmt.Fprintln(stderr, s.err.Error())
+		return exitCode(s.err)
+	}
+
+	rep := newReport(server, s.resp, s.err)
+	if asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rep); err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return exitError
+		}
+	} else {
+		rep.writeText(stdout)
+	}
+
+	if s.err != nil {
+		fmt.Fprintln(stderr, s.err.Error())
+		return exitError
+	}
+	return exitOK
+}
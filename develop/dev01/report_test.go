@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/beevik/ntp"
+	"github.com/stretchr/testify/require"
+)
+
+type refIDTest struct {
+	name    string
+	stratum uint8
+	id      uint32
+	exp     string
+}
+
+var refIDTests = []refIDTest{
+	{name: "test1", stratum: 1, id: 0x47505300, exp: "GPS"},
+	{name: "test2", stratum: 0, id: 0x52415445, exp: "RATE"},
+	{name: "test3", stratum: 2, id: 0xc0a80101, exp: "192.168.1.1"},
+}
+
+func TestReferenceID(t *testing.T) {
+	for _, test := range refIDTests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.exp, referenceID(test.stratum, test.id))
+		})
+	}
+}
+
+func TestLeapString(t *testing.T) {
+	require.Equal(t, "none", leapString(ntp.LeapNoWarning))
+	require.Equal(t, "add_second", leapString(ntp.LeapAddSecond))
+	require.Equal(t, "del_second", leapString(ntp.LeapDelSecond))
+	require.Equal(t, "not_in_sync", leapString(ntp.LeapNotInSync))
+}
+
+func TestRunReport(t *testing.T) {
+	server := testServer(t, 0)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-report", "-host", server}, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Contains(t, stdout.String(), "stratum:          1\n")
+	require.Contains(t, stdout.String(), "reference id:     LOCL\n")
+	require.Contains(t, stdout.String(), "leap indicator:   none\n")
+
+	stdout.Reset()
+	code = run([]string{"-report", "-json", "-host", server}, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	var rep report
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &rep))
+	require.Equal(t, server, rep.Server)
+	require.Equal(t, uint8(1), rep.Stratum)
+	require.Equal(t, "LOCL", rep.ReferenceID)
+	require.InDelta(t, 0.01, rep.RootDispersion, 0.001)
+	require.InDelta(t, 0, rep.ClockOffset, 0.1)
+	require.Empty(t, rep.ValidationError)
+
+	code = run([]string{"-json"}, &stdout, &stderr)
+	require.Equal(t, exitUsage, code)
+}
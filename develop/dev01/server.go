@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/beevik/ntp"
+)
+
+// Параметры ответов SNTP-сервера. Сервер считает себя первичным (stratum 1),
+// синхронизированным с локальными часами.
+const (
+	serverStratum    = 1
+	serverPrecision  = -20 // ~1 мкс
+	serverDispersion = 10 * time.Millisecond
+	serverRefID      = 0x4c4f434c // "LOCL"
+)
+
+// sntpServer - минимальный SNTPv4-сервер (RFC 4330), отвечающий временем функции clock.
+// Если заданы ключи, подписанные запросы проверяются и ответ подписывается тем же ключом.
+type sntpServer struct {
+	conn  net.PacketConn
+	clock func() time.Time
+	keys  map[uint32]symKey
+}
+
+// newSNTPServer - открывает UDP-сокет на адресе addr и создаёт сервер с часами clock.
+func newSNTPServer(addr string, clock func() time.Time) (*sntpServer, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &sntpServer{conn: conn, clock: clock}, nil
+}
+
+// skewedClock - возвращает часы, отстающие или спешащие относительно локальных на skew.
+// Если задан start, часы начинают отсчёт с этого момента.
+func skewedClock(start time.Time, skew time.Duration) func() time.Time {
+	if start.IsZero() {
+		return func() time.Time { return time.Now().Add(skew) }
+	}
+	origin := time.Now()
+	return func() time.Time { return start.Add(time.Since(origin) + skew) }
+}
+
+// addr - адрес, на котором сервер принимает запросы.
+func (s *sntpServer) addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// close - останавливает сервер.
+func (s *sntpServer) close() error {
+	return s.conn.Close()
+}
+
+// serve - принимает запросы до закрытия сокета. После close возвращает nil.
+func (s *sntpServer) serve() error {
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		recv := s.clock()
+		resp := s.reply(buf[:n], recv)
+		if resp == nil {
+			continue
+		}
+		s.conn.WriteTo(resp, addr)
+	}
+}
+
+// reply - формирует ответ на запрос клиента, полученный в момент recv.
+// Некорректные запросы и запросы не в режиме клиента остаются без ответа (nil).
+func (s *sntpServer) reply(req []byte, recv time.Time) []byte {
+	p, err := parsePacket(req)
+	if err != nil || p.mode != modeClient || p.version < 1 || p.version > 4 {
+		return nil
+	}
+	resp := &packet{
+		leap:           ntp.LeapNoWarning,
+		version:        p.version,
+		mode:           modeServer,
+		stratum:        serverStratum,
+		poll:           p.poll,
+		precision:      serverPrecision,
+		rootDispersion: toNtpShort(serverDispersion),
+		referenceID:    serverRefID,
+		referenceTime:  toNtpTime(recv),
+		originTime:     p.transmitTime,
+		receiveTime:    toNtpTime(recv),
+		transmitTime:   toNtpTime(s.clock()),
+	}
+	if s.keys == nil || len(req) == packetLen {
+		return resp.marshal()
+	}
+
+	// Запрос с подписью: при неизвестном ключе или неверной подписи отвечаем crypto-NAK
+	// (RFC 5905, раздел 7.4) - нулевым идентификатором ключа без подписи.
+	var k symKey
+	ok := false
+	if len(req) >= packetLen+4 {
+		k, ok = s.keys[binary.BigEndian.Uint32(req[packetLen:])]
+	}
+	if !ok || verifyMAC(req, k) != nil {
+		return appendUint32(resp.marshal(), 0)
+	}
+	return k.appendMAC(resp.marshal())
+}
+
+// runServe - режим SNTP-сервера. Работает до отмены контекста.
+func runServe(ctx context.Context, addr string, clock func() time.Time, keys map[uint32]symKey, stderr io.Writer) int {
+	s, err := newSNTPServer(addr, clock)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return exitError
+	}
+	s.keys = keys
+	go func() {
+		<-ctx.Done()
+		s.close()
+	}()
+	fmt.Fprintf(stderr, "serving SNTP on %s\n", s.addr())
+	if err := s.serve(); err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/beevik/ntp"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer - запускает SNTP-сервер на localhost с часами, сдвинутыми на skew.
+// Возвращает адрес сервера в виде host:port.
+func testServer(t *testing.T, skew time.Duration) string {
+	s, err := newSNTPServer("127.0.0.1:0", skewedClock(time.Time{}, skew))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.close() })
+	go s.serve()
+	return s.addr().String()
+}
+
+func TestPacketMarshal(t *testing.T) {
+	p := &packet{
+		leap:           ntp.LeapAddSecond,
+		version:        4,
+		mode:           modeServer,
+		stratum:        2,
+		poll:           6,
+		precision:      -20,
+		rootDelay:      toNtpShort(time.Second / 4),
+		rootDispersion: toNtpShort(time.Second / 2),
+		referenceID:    serverRefID,
+		referenceTime:  toNtpTime(time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)),
+		transmitTime:   1,
+	}
+	b := p.marshal()
+	require.Len(t, b, packetLen)
+	require.Equal(t, uint8(1<<6|4<<3|4), b[0])
+
+	res, err := parsePacket(b)
+	require.NoError(t, err)
+	require.Equal(t, p, res)
+	require.Equal(t, time.Second/4, fromNtpShort(res.rootDelay))
+	require.Equal(t, time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC), fromNtpTime(res.referenceTime))
+
+	_, err = parsePacket(b[:47])
+	require.Equal(t, ErrShortPacket, err)
+}
+
+func TestServerReply(t *testing.T) {
+	now := time.Date(2022, 12, 1, 12, 0, 0, 0, time.UTC)
+	s := &sntpServer{clock: func() time.Time { return now }}
+
+	req := &packet{version: 3, mode: modeClient, poll: 4, transmitTime: 12345}
+	res, err := parsePacket(s.reply(req.marshal(), now))
+	require.NoError(t, err)
+	require.Equal(t, uint8(3), res.version)
+	require.Equal(t, uint8(modeServer), res.mode)
+	require.Equal(t, uint8(serverStratum), res.stratum)
+	require.Equal(t, int8(4), res.poll)
+	require.Equal(t, uint64(12345), res.originTime)
+	require.Equal(t, now, fromNtpTime(res.transmitTime))
+
+	req.mode = modeServer
+	require.Nil(t, s.reply(req.marshal(), now))
+	require.Nil(t, s.reply([]byte{0x1b}, now))
+}
+
+func TestSkewedClock(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := skewedClock(start, time.Hour)
+	require.WithinDuration(t, start.Add(time.Hour), clock(), time.Second)
+
+	clock = skewedClock(time.Time{}, -time.Hour)
+	require.WithinDuration(t, time.Now().Add(-time.Hour), clock(), time.Second)
+}
+
+func TestRunServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := newSNTPServer("127.0.0.1:0", skewedClock(time.Time{}, 2*time.Second))
+	require.NoError(t, err)
+	addr := s.addr().String()
+	s.close()
+
+	done := make(chan int)
+	var serveErr bytes.Buffer
+	go func() { done <- runServe(ctx, addr, skewedClock(time.Time{}, 2*time.Second), nil, &serveErr) }()
+
+	// Клиентская часть программы должна получить время сервера целиком офлайн.
+	var stdout, stderr bytes.Buffer
+	require.Eventually(t, func() bool {
+		stdout.Reset()
+		stderr.Reset()
+		return run([]string{"-host", addr}, &stdout, &stderr) == exitOK
+	}, 5*time.Second, 50*time.Millisecond, stderr.String())
+	require.NotEmpty(t, stdout.String())
+
+	stdout.Reset()
+	require.Equal(t, exitOK, run([]string{"-report", "-json", "-host", addr}, &stdout, &stderr), stderr.String())
+	var rep report
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &rep))
+	require.InDelta(t, 2, rep.ClockOffset, 0.5)
+
+	cancel()
+	require.Equal(t, exitOK, <-done, serveErr.String())
+}
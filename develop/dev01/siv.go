@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+// sivKeyLen - длина ключа AEAD_AES_SIV_CMAC_256: две половины по 128 бит для CMAC и CTR.
+const sivKeyLen = 32
+
+// ErrSIVAuth - ошибка проверки целостности при расшифровке AES-SIV.
+var ErrSIVAuth = errors.New("aes-siv: message authentication failed")
+
+// siv - AEAD_AES_SIV_CMAC_256 (RFC 5297). Одноразовое число (nonce) передаётся
+// последним компонентом связанных данных, как описано в RFC 5297, раздел 3.
+type siv struct {
+	mac cipher.Block // K1 - для S2V (CMAC)
+	ctr cipher.Block // K2 - для шифрования в режиме CTR
+}
+
+// newSIV - создаёт AES-SIV по 256-битному ключу.
+func newSIV(key []byte) (*siv, error) {
+	if len(key) != sivKeyLen {
+		return nil, errors.New("aes-siv: invalid key length")
+	}
+	mac, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[16:])
+	if err != nil {
+		return nil, err
+	}
+	return &siv{mac: mac, ctr: ctr}, nil
+}
+
+// seal - шифрует plaintext. Результат - синтетический вектор V (16 байт) и шифротекст.
+func (s *siv) seal(nonce, plaintext []byte, ad ...[]byte) []byte {
+	v := s.s2v(sivStrings(ad, nonce, plaintext)...)
+	out := make([]byte, aes.BlockSize+len(plaintext))
+	copy(out, v)
+	s.xorCTR(out[aes.BlockSize:], plaintext, v)
+	return out
+}
+
+// open - расшифровывает и проверяет результат seal.
+func (s *siv) open(nonce, ciphertext []byte, ad ...[]byte) ([]byte, error) {
+	if len(ciphertext) < aes.BlockSize {
+		return nil, ErrSIVAuth
+	}
+	v := ciphertext[:aes.BlockSize]
+	plaintext := make([]byte, len(ciphertext)-aes.BlockSize)
+	s.xorCTR(plaintext, ciphertext[aes.BlockSize:], v)
+	if subtle.ConstantTimeCompare(v, s.s2v(sivStrings(ad, nonce, plaintext)...)) != 1 {
+		return nil, ErrSIVAuth
+	}
+	return plaintext, nil
+}
+
+// sivStrings - набор строк для S2V: связанные данные, nonce и открытый текст.
+func sivStrings(ad [][]byte, nonce, plaintext []byte) [][]byte {
+	strs := make([][]byte, 0, len(ad)+2)
+	strs = append(strs, ad...)
+	return append(strs, nonce, plaintext)
+}
+
+// xorCTR - шифрует src в режиме CTR со счётчиком, полученным из V обнулением 31-го и 63-го битов.
+func (s *siv) xorCTR(dst, src, v []byte) {
+	q := make([]byte, aes.BlockSize)
+	copy(q, v)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	cipher.NewCTR(s.ctr, q).XORKeyStream(dst, src)
+}
+
+// s2v - псевдослучайная функция S2V над набором строк (RFC 5297, раздел 2.4).
+func (s *siv) s2v(strs ...[]byte) []byte {
+	d := s.cmac(make([]byte, aes.BlockSize))
+	for _, str := range strs[:len(strs)-1] {
+		d = dbl(d)
+		xorBytes(d, s.cmac(str))
+	}
+	last := strs[len(strs)-1]
+	var t []byte
+	if len(last) >= aes.BlockSize {
+		t = append([]byte(nil), last...)
+		xorBytes(t[len(t)-aes.BlockSize:], d)
+	} else {
+		t = dbl(d)
+		xorBytes(t, pad(last))
+	}
+	return s.cmac(t)
+}
+
+// cmac - AES-CMAC (RFC 4493) с ключом K1.
+func (s *siv) cmac(msg []byte) []byte {
+	l := make([]byte, aes.BlockSize)
+	s.mac.Encrypt(l, l)
+	k1 := dbl(l)
+	k2 := dbl(k1)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	var last []byte
+	if n > 0 && len(msg)%aes.BlockSize == 0 {
+		last = append([]byte(nil), msg[(n-1)*aes.BlockSize:]...)
+		xorBytes(last, k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		last = pad(msg[(n-1)*aes.BlockSize:])
+		xorBytes(last, k2)
+	}
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		xorBytes(x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		s.mac.Encrypt(x, x)
+	}
+	xorBytes(x, last)
+	s.mac.Encrypt(x, x)
+	return x
+}
+
+// dbl - умножение на x в поле GF(2^128).
+func dbl(b []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	var carry byte
+	for i := aes.BlockSize - 1; i >= 0; i-- {
+		out[i] = b[i]<<1 | carry
+		carry = b[i] >> 7
+	}
+	if carry != 0 {
+		out[aes.BlockSize-1] ^= 0x87
+	}
+	return out
+}
+
+// pad - дополняет неполный блок битом 1 и нулями.
+func pad(b []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	copy(out, b)
+	out[len(b)] = 0x80
+	return out
+}
+
+// xorBytes - dst ^= src по длине src.
+func xorBytes(dst, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
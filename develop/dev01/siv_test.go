@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// unhex - декодирует шестнадцатеричную строку, допускающую пробелы.
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	require.NoError(t, err)
+	return b
+}
+
+func TestCMAC(t *testing.T) {
+	// Тестовые векторы RFC 4493, раздел 4.
+	key := unhex(t, "2b7e1516 28aed2a6 abf71588 09cf4f3c")
+	s, err := newSIV(append(key, key...))
+	require.NoError(t, err)
+	require.Equal(t, unhex(t, "bb1d6929 e9593728 7fa37d12 9b756746"), s.cmac(nil))
+	require.Equal(t, unhex(t, "070a16b4 6b4d4144 f79bdd9d d04a287c"),
+		s.cmac(unhex(t, "6bc1bee2 2e409f96 e93d7e11 7393172a")))
+	require.Equal(t, unhex(t, "dfa66747 de9ae630 30ca3261 1497c827"),
+		s.cmac(unhex(t, "6bc1bee2 2e409f96 e93d7e11 7393172a ae2d8a57 1e03ac9c 9eb76fac 45af8e51 30c81c46 a35ce411")))
+}
+
+func TestSIV(t *testing.T) {
+	// Тестовый вектор RFC 5297, приложение A.2 (с nonce).
+	s, err := newSIV(unhex(t, "7f7e7d7c 7b7a7978 77767574 73727170 40414243 44454647 48494a4b 4c4d4e4f"))
+	require.NoError(t, err)
+	ad1 := unhex(t, "00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100")
+	ad2 := unhex(t, "10203040 50607080 90a0")
+	nonce := unhex(t, "09f91102 9d74e35b d84156c5 635688c0")
+	plaintext := unhex(t, "74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 74207573 696e6720 5349562d 414553")
+	exp := unhex(t, "7bdb6e3b 432667eb 06f4d14b ff2fbd0f cb900f2f ddbe4043 26601965 c889bf17 dba77ceb 094fa663 b7a3f748 ba8af829 ea64ad54 4a272e9c 485b62a3 fd5c0d")
+
+	ciphertext := s.seal(nonce, plaintext, ad1, ad2)
+	require.Equal(t, exp, ciphertext)
+
+	res, err := s.open(nonce, ciphertext, ad1, ad2)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, res)
+
+	ciphertext[20] ^= 1
+	_, err = s.open(nonce, ciphertext, ad1, ad2)
+	require.Equal(t, ErrSIVAuth, err)
+
+	_, err = s.open(nonce, exp, ad1)
+	require.Equal(t, ErrSIVAuth, err)
+}
+
+func TestS2V(t *testing.T) {
+	// Тестовый вектор RFC 5297, приложение A.1 (без nonce).
+	s, err := newSIV(unhex(t, "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff"))
+	require.NoError(t, err)
+	ad := unhex(t, "10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627")
+	plaintext := unhex(t, "11223344 55667788 99aabbcc ddee")
+	v := s.s2v(ad, plaintext)
+	require.Equal(t, unhex(t, "85632d07 c6e8f37f 950acd32 0a2ecc93"), v)
+
+	ciphertext := make([]byte, len(plaintext))
+	s.xorCTR(ciphertext, plaintext, v)
+	require.Equal(t, unhex(t, "40c02b96 90c4dc04 daef7f6a fe5c"), ciphertext)
+}
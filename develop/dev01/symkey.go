@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/beevik/ntp"
+)
+
+// ErrAuthFailed - ошибка проверки подписи ответа сервера симметричным ключом.
+var ErrAuthFailed = errors.New("ntp: symmetric key authentication failed")
+
+// symKey - симметричный ключ из файла ntp.keys.
+type symKey struct {
+	id     uint32
+	typ    string // MD5 или SHA1
+	secret []byte
+}
+
+// digestLen - длина подписи для типа ключа.
+func (k symKey) digestLen() int {
+	if k.typ == "SHA1" {
+		return sha1.Size
+	}
+	return md5.Size
+}
+
+// mac - подпись данных в формате ntpd: хеш от ключа, за которым следует пакет.
+func (k symKey) mac(data []byte) []byte {
+	var h hash.Hash
+	if k.typ == "SHA1" {
+		h = sha1.New()
+	} else {
+		h = md5.New()
+	}
+	h.Write(k.secret)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// appendMAC - добавляет к пакету идентификатор ключа и подпись.
+func (k symKey) appendMAC(b []byte) []byte {
+	digest := k.mac(b)
+	b = appendUint32(b, k.id)
+	return append(b, digest...)
+}
+
+// loadKeys - читает ключи из файла в формате ntp.keys.
+func loadKeys(path string) (map[uint32]symKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	keys, err := parseKeys(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// parseKeys - разбирает строки вида "keyid type key". Комментарии начинаются с #.
+// Тип - MD5 (или M) либо SHA1 (или SHA). Ключ длиной до 20 символов задаётся как ASCII-строка,
+// более длинный - в шестнадцатеричном виде, как в ntpd.
+func parseKeys(r io.Reader) (map[uint32]symKey, error) {
+	keys := make(map[uint32]symKey)
+	buf := bufio.NewScanner(r)
+	for line := 1; buf.Scan(); line++ {
+		text := buf.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected \"keyid type key\"", line)
+		}
+
+		id, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("line %d: invalid key id %q", line, fields[0])
+		}
+		k := symKey{id: uint32(id)}
+		switch strings.ToUpper(fields[1]) {
+		case "M", "MD5":
+			k.typ = "MD5"
+		case "SHA", "SHA1":
+			k.typ = "SHA1"
+		default:
+			return nil, fmt.Errorf("line %d: unsupported key type %q", line, fields[1])
+		}
+		if len(fields[2]) <= 20 {
+			k.secret = []byte(fields[2])
+		} else if k.secret, err = hex.DecodeString(fields[2]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid hex key", line)
+		}
+		keys[k.id] = k
+	}
+	if err := buf.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// verifyMAC - проверяет подпись пакета raw ключом k. Ответ без подписи, подписанный другим
+// ключом или crypto-NAK (только нулевой идентификатор ключа) считаются ошибкой ErrAuthFailed.
+func verifyMAC(raw []byte, k symKey) error {
+	switch len(raw) - packetLen {
+	case 0:
+		return fmt.Errorf("%w: response is not signed", ErrAuthFailed)
+	case 4:
+		return fmt.Errorf("%w: server sent crypto-NAK", ErrAuthFailed)
+	case 4 + k.digestLen():
+	default:
+		return fmt.Errorf("%w: unexpected MAC length", ErrAuthFailed)
+	}
+	if id := binary.BigEndian.Uint32(raw[packetLen:]); id != k.id {
+		return fmt.Errorf("%w: response signed with key %d", ErrAuthFailed, id)
+	}
+	if subtle.ConstantTimeCompare(raw[packetLen+4:], k.mac(raw[:packetLen])) != 1 {
+		return fmt.Errorf("%w: invalid MAC", ErrAuthFailed)
+	}
+	return nil
+}
+
+// authQuery - запрос времени, подписанный симметричным ключом, с проверкой подписи ответа.
+func authQuery(k symKey, opt ntp.QueryOptions) queryFunc {
+	return func(server string) sample {
+		s := sample{server: server}
+		s.resp, s.err = queryAuth(server, k, opt)
+		if s.err == nil {
+			s.err = s.resp.Validate()
+		}
+		return s
+	}
+}
+
+// queryAuth - отправляет подписанный запрос и проверяет подпись ответа.
+func queryAuth(server string, k symKey, opt ntp.QueryOptions) (*ntp.Response, error) {
+	host, port, err := serverAddr(server)
+	if err != nil {
+		return nil, err
+	}
+	if port != 0 {
+		opt.Port = port
+	}
+	version := opt.Version
+	if version == 0 {
+		version = 4
+	}
+	req, err := clientRequest(version)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, xmit, recv, err := exchange(host, opt, k.appendMAC(req.marshal()))
+	if err != nil {
+		return nil, err
+	}
+	p, err := parsePacket(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(req, p); err != nil {
+		return nil, err
+	}
+	if err := verifyMAC(raw, k); err != nil {
+		return nil, err
+	}
+	return newResponse(p, xmit, recv), nil
+}
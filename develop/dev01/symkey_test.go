@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testKeys = `# ntp.keys
+1 M secret1
+2 MD5 secret2   # комментарий
+3 SHA1 0102030405060708090a0b0c0d0e0f1011121314
+
+10 SHA anothersecret
+`
+
+// keyedServer - запускает SNTP-сервер с ключами keys и возвращает его адрес.
+func keyedServer(t *testing.T, keys map[uint32]symKey) string {
+	s, err := newSNTPServer("127.0.0.1:0", skewedClock(time.Time{}, 0))
+	require.NoError(t, err)
+	s.keys = keys
+	t.Cleanup(func() { s.close() })
+	go s.serve()
+	return s.addr().String()
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := parseKeys(strings.NewReader(testKeys))
+	require.NoError(t, err)
+	require.Len(t, keys, 4)
+	require.Equal(t, symKey{id: 1, typ: "MD5", secret: []byte("secret1")}, keys[1])
+	require.Equal(t, symKey{id: 2, typ: "MD5", secret: []byte("secret2")}, keys[2])
+	require.Equal(t, "SHA1", keys[3].typ)
+	require.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}, keys[3].secret)
+	require.Equal(t, "SHA1", keys[10].typ)
+
+	_, err = parseKeys(strings.NewReader("1 M"))
+	require.EqualError(t, err, `line 1: expected "keyid type key"`)
+	_, err = parseKeys(strings.NewReader("\n0 M secret"))
+	require.EqualError(t, err, `line 2: invalid key id "0"`)
+	_, err = parseKeys(strings.NewReader("1 AES128CMAC secret"))
+	require.EqualError(t, err, `line 1: unsupported key type "AES128CMAC"`)
+	_, err = parseKeys(strings.NewReader("1 SHA1 zz02030405060708090a0b0c0d0e0f1011121314"))
+	require.EqualError(t, err, "line 1: invalid hex key")
+}
+
+func TestVerifyMAC(t *testing.T) {
+	k := symKey{id: 5, typ: "SHA1", secret: []byte("secret")}
+	b := k.appendMAC((&packet{version: 4, mode: modeServer}).marshal())
+	require.Len(t, b, packetLen+4+20)
+	require.NoError(t, verifyMAC(b, k))
+
+	other := symKey{id: 6, typ: "SHA1", secret: []byte("secret")}
+	require.True(t, errors.Is(verifyMAC(b, other), ErrAuthFailed))
+	require.True(t, errors.Is(verifyMAC(b[:packetLen], k), ErrAuthFailed))
+	require.True(t, errors.Is(verifyMAC(b[:packetLen+4], k), ErrAuthFailed))
+
+	b[10] ^= 1
+	require.EqualError(t, verifyMAC(b, k), ErrAuthFailed.Error()+": invalid MAC")
+}
+
+func TestRunSymmetricKey(t *testing.T) {
+	keys, err := parseKeys(strings.NewReader(testKeys))
+	require.NoError(t, err)
+	server := keyedServer(t, keys)
+	keysFile := filepath.Join(t.TempDir(), "ntp.keys")
+	require.NoError(t, os.WriteFile(keysFile, []byte(testKeys), 0o600))
+
+	for _, id := range []int{1, 3} {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"-host", server, "-keys", keysFile, "-keyid", strconv.Itoa(id)}, &stdout, &stderr)
+		require.Equal(t, exitOK, code, stderr.String())
+	}
+
+	// Клиент с другим значением ключа 2 получает crypto-NAK.
+	wrongFile := filepath.Join(t.TempDir(), "wrong.keys")
+	require.NoError(t, os.WriteFile(wrongFile, []byte("2 MD5 notsecret\n"), 0o600))
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-host", server, "-keys", wrongFile, "-keyid", "2"}, &stdout, &stderr)
+	require.Equal(t, exitAuth, code)
+	require.Contains(t, stderr.String(), "crypto-NAK")
+
+	// Сервер без ключей отвечает без подписи.
+	stderr.Reset()
+	code = run([]string{"-host", testServer(t, 0), "-keys", keysFile, "-keyid", "1", "-report"}, &stdout, &stderr)
+	require.Equal(t, exitAuth, code)
+	require.Contains(t, stderr.String(), "response is not signed")
+
+	stderr.Reset()
+	code = run([]string{"-host", server, "-keys", keysFile, "-keyid", "7"}, &stdout, &stderr)
+	require.Equal(t, exitUsage, code)
+	require.Equal(t, "key 7 not found, check -keys\n", stderr.String())
+}
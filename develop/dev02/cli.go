@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// Коды выхода программы.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// usage - краткая справка по подкомандам.
+const usage = `usage:
+  dev02 pack [-o FILE] [FILE...]
+  dev02 unpack [-o FILE] [-check] [-multi] [-max-run N] [-max-output N]
+               [-escape C] [-braces] [-groups] [FILE...]
+Без файлов или с файлом "-" читается stdin.`
+
+// run - разбирает подкоманду и её аргументы. Ошибки выводятся в stderr,
+// возвращаемое значение - код выхода для ОС.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, usage)
+		return exitUsage
+	}
+	cmd := args[0]
+	if cmd != "pack" && cmd != "unpack" {
+		fmt.Fprintf(stderr, "unknown command %q\n%s\n", cmd, usage)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("dev02 "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "файл для результата, по умолчанию stdout; может совпадать с одним из входов")
+	var opt UnpackOptions
+	var check *bool
+	var escape *string
+	if cmd == "unpack" {
+		check = fs.Bool("check", false, "только проверить входные данные и сообщить о первой ошибке")
+		fs.BoolVar(&opt.MultiDigit, "multi", false, "многозначные счётчики: a12 - двенадцать символов a")
+		fs.IntVar(&opt.MaxRun, "max-run", 0, "наибольший счётчик, 0 - 9 или DefaultMaxCount при -multi")
+		fs.Int64Var(&opt.MaxOutput, "max-output", 0, "наибольший размер результата для каждого входа в байтах, 0 - без ограничения")
+		escape = fs.String("escape", `\`, "экранирующий символ")
+		fs.BoolVar(&opt.Grammar.Braces, "braces", false, "счётчики в фигурных скобках: a{12}")
+		fs.BoolVar(&opt.Grammar.Groups, "groups", false, "группы в круглых скобках: (ab)3")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return exitUsage
+	}
+	if escape != nil {
+		r, size := utf8.DecodeRuneInString(*escape)
+		if size == 0 || size != len(*escape) {
+			fmt.Fprintln(stderr, "-escape must be a single character")
+			return exitUsage
+		}
+		opt.Grammar.Escape = r
+		if err := opt.Grammar.validate(); err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return exitUsage
+		}
+	}
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	var w io.Writer = stdout
+	var out *atomicFile
+	switch {
+	case check != nil && *check:
+		w = io.Discard
+	case *output != "":
+		var err error
+		if out, err = createAtomic(*output); err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return exitError
+		}
+		defer out.abort()
+		w = out
+	}
+
+	for _, name := range inputs {
+		var err error
+		if cmd == "pack" {
+			err = withInput(name, stdin, func(r io.Reader) error { return packStream(r, w) })
+		} else {
+			err = withInput(name, stdin, func(r io.Reader) error { return NewUnpackerOptions(r, opt).Unpack(w) })
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", displayName(name), err)
+			return exitError
+		}
+	}
+	if out != nil {
+		if err := out.commit(); err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return exitError
+		}
+	}
+	return exitOK
+}
+
+// withInput - открывает вход name ("-" - stdin) и передаёт его в f.
+func withInput(name string, stdin io.Reader, f func(io.Reader) error) error {
+	if name == "-" {
+		return f(stdin)
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return f(file)
+}
+
+// packStream - упаковывает весь вход r и пишет результат в w.
+func packStream(r io.Reader, w io.Writer) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, PackStr(string(b)))
+	return err
+}
+
+// displayName - имя входа для сообщений об ошибках.
+func displayName(name string) string {
+	if name == "-" {
+		return "stdin"
+	}
+	return name
+}
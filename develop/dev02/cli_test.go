@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var runTests = []struct {
+	name   string
+	args   []string
+	stdin  string
+	code   int
+	stdout string
+	stderr string
+}{
+	{name: "test1", args: []string{"pack"}, stdin: "aaaabccddddde", stdout: "a4bc2d5e"},
+	{name: "test2", args: []string{"unpack"}, stdin: "a4bc2d5e", stdout: "aaaabccddddde"},
+	{name: "test3", args: []string{"unpack", "-"}, stdin: `qwe\45`, stdout: "qwe44444"},
+	{name: "test4", args: []string{"unpack", "-multi"}, stdin: "a12", stdout: "aaaaaaaaaaaa"},
+	{name: "test5", args: []string{"unpack"}, stdin: "ab12", code: exitError, stdout: "ab", stderr: "stdin: invalid string: adjacent digits at offset 3 (rune 3)\n"},
+	{name: "test6", args: []string{"unpack", "-check"}, stdin: "a4bc2d5e", code: exitOK},
+	{name: "test7", args: []string{"unpack", "-check"}, stdin: `qwe\\5\`, code: exitError, stderr: "stdin: invalid string: trailing escape at offset 6 (rune 6)\n"},
+	{name: "test8", args: []string{"unpack", "-max-output", "5"}, stdin: "a9", code: exitError, stdout: "a", stderr: "stdin: unpacked string too large: limit exceeded at offset 1\n"},
+	{name: "test9", args: nil, code: exitUsage, stderr: usage + "\n"},
+	{name: "test10", args: []string{"zip"}, code: exitUsage, stderr: "unknown command \"zip\"\n" + usage + "\n"},
+	{name: "test11", args: []string{"pack", "-check"}, code: exitUsage},
+	{name: "test12", args: []string{"unpack", "-groups", "-braces", "-escape", "%"}, stdin: "(a%(){3}", stdout: "a(a(a("},
+	{name: "test13", args: []string{"unpack", "-escape", "ab"}, code: exitUsage, stderr: "-escape must be a single character\n"},
+	{name: "test14", args: []string{"unpack", "-escape", "7"}, code: exitUsage, stderr: "invalid grammar: escape '7' conflicts with count syntax\n"},
+}
+
+func TestRun(t *testing.T) {
+	for _, test := range runTests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+			require.Equal(t, test.code, code, stderr.String())
+			require.Equal(t, test.stdout, stdout.String())
+			if test.code != exitUsage || test.stderr != "" {
+				require.Equal(t, test.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	in1 := filepath.Join(dir, "in1.txt")
+	in2 := filepath.Join(dir, "in2.txt")
+	packed := filepath.Join(dir, "packed.txt")
+	require.NoError(t, os.WriteFile(in1, []byte("aaaaa11\n"), 0o600))
+	require.NoError(t, os.WriteFile(in2, []byte(`\\\\\\`), 0o600))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"pack", "-o", packed, in1, in2}, nil, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Empty(t, stdout.String())
+	b, err := os.ReadFile(packed)
+	require.NoError(t, err)
+	require.Equal(t, "a5\\12\n\\\\6", string(b))
+
+	code = run([]string{"unpack", packed}, nil, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Equal(t, "aaaaa11\n"+`\\\\\\`, stdout.String())
+
+	code = run([]string{"unpack", filepath.Join(dir, "missing.txt")}, nil, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "missing.txt")
+}
+
+func TestRunInPlace(t *testing.T) {
+	dir := t.TempDir()
+	f := filepath.Join(dir, "data.txt")
+	require.NoError(t, os.WriteFile(f, []byte("aaaabccddddde"), 0o640))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"pack", "-o", f, f}, nil, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	b, err := os.ReadFile(f)
+	require.NoError(t, err)
+	require.Equal(t, "a4bc2d5e", string(b))
+	fi, err := os.Stat(f)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o640), fi.Mode().Perm())
+
+	code = run([]string{"unpack", "-o", f, f}, nil, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	b, err = os.ReadFile(f)
+	require.NoError(t, err)
+	require.Equal(t, "aaaabccddddde", string(b))
+
+	// Ошибка распаковки не оставляет частичный результат и не портит прежний файл.
+	out := filepath.Join(dir, "out.txt")
+	require.NoError(t, os.WriteFile(out, []byte("old"), 0o600))
+	code = run([]string{"unpack", "-o", out}, strings.NewReader("ab12"), &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	b, err = os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "old", string(b))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
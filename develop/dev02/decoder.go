@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// position - позиция символа во входе.
+type position struct {
+	index  int   // номер символа
+	offset int64 // смещение в байтах
+}
+
+// state - состояние декодера.
+type state int
+
+// Состояния декодера.
+const (
+	stText   state = iota // обычный текст
+	stEscape              // после экранирующего символа
+	stDigit               // после однозначного счётчика
+	stDigits              // многозначный счётчик из цифр
+	stBrace               // счётчик в фигурных скобках
+	numStates
+)
+
+// class - класс входного символа. Служебные символы отключённых конструкций грамматики
+// относятся к clsOther.
+type class int
+
+// Классы входных символов.
+const (
+	clsOther      class = iota // обычный символ
+	clsEscape                  // экранирующий символ
+	clsDigit                   // цифра
+	clsBraceOpen               // {
+	clsBraceClose              // }
+	clsGroupOpen               // (
+	clsGroupClose              // )
+	numClasses
+)
+
+// action - действие декодера на переходе.
+type action int
+
+// Действия декодера.
+const (
+	actLiteral  action = iota // записать символ
+	actEscape                 // запомнить экранирующий символ
+	actCount                  // начать счётчик с цифры
+	actBrace                  // начать счётчик в фигурных скобках
+	actDigit                  // добавить цифру к счётчику
+	actBraceEnd               // закончить счётчик в фигурных скобках
+	actEndCount               // закончить счётчик и обработать символ в состоянии stText
+	actOpen                   // открыть группу
+	actClose                  // закрыть группу
+	actBadBrace               // ошибка: неожиданная фигурная скобка или не цифра в счётчике
+)
+
+// transition - переход автомата: действие и следующее состояние.
+type transition struct {
+	act  action
+	next state
+}
+
+// newTable - таблица переходов для параметров opt. Однозначный и многозначный счётчики
+// различаются состоянием после первой цифры.
+func newTable(opt UnpackOptions) *[numStates][numClasses]transition {
+	var t [numStates][numClasses]transition
+	count := stDigit
+	if opt.MultiDigit {
+		count = stDigits
+	}
+	t[stText] = [numClasses]transition{
+		clsOther:      {actLiteral, stText},
+		clsEscape:     {actEscape, stEscape},
+		clsDigit:      {actCount, count},
+		clsBraceOpen:  {actBrace, stBrace},
+		clsBraceClose: {actBadBrace, stText},
+		clsGroupOpen:  {actOpen, stText},
+		clsGroupClose: {actClose, stText},
+	}
+	for c := class(0); c < numClasses; c++ {
+		t[stEscape][c] = transition{actLiteral, stText}
+		t[stDigit][c] = transition{actEndCount, stText}
+		t[stDigits][c] = transition{actEndCount, stText}
+		t[stBrace][c] = transition{actBadBrace, stBrace}
+	}
+	t[stDigits][clsDigit] = transition{actDigit, stDigits}
+	t[stBrace][clsDigit] = transition{actDigit, stBrace}
+	t[stBrace][clsBraceClose] = transition{actBraceEnd, stText}
+	return &t
+}
+
+// group - открытая группа: её результат накапливается до закрывающей скобки и счётчика.
+type group struct {
+	buf []byte
+	pos position
+}
+
+// decoder - пошаговая распаковка, общая для UnpackStr и Unpacker: конечный автомат с таблицей
+// переходов по состоянию и классу символа. Получает символы входа по одному и пишет результат в w.
+// Счётчик применяется к символу или группе, когда он закончился: на следующем символе или в конце входа.
+type decoder struct {
+	w       io.Writer
+	opt     UnpackOptions
+	table   *[numStates][numClasses]transition
+	escape  rune
+	maxRun  int
+	written int64 // размер результата, включая содержимое открытых групп
+
+	state   state
+	prev    []byte  // символ или результат группы, который может повторить следующий счётчик
+	count   int     // накопленный счётчик
+	counted bool    // последней была запись счётчика: следующий счётчик некорректен
+	groups  []group // открытые группы, последняя - текущая
+
+	pos      position // позиция следующего символа
+	escPos   position // позиция последнего экранирующего символа
+	countPos position // позиция начала текущего счётчика
+}
+
+// newDecoder - создаёт декодер с параметрами opt, пишущий в w. Грамматика opt должна быть проверена.
+func newDecoder(w io.Writer, opt UnpackOptions) *decoder {
+	return &decoder{w: w, opt: opt, table: newTable(opt), escape: opt.Grammar.escape(), maxRun: opt.maxRun()}
+}
+
+// classify - класс символа r в грамматике декодера.
+func (d *decoder) classify(r rune) class {
+	g := d.opt.Grammar
+	switch {
+	case r == d.escape:
+		return clsEscape
+	case isDigit(r):
+		return clsDigit
+	case g.Braces && r == '{':
+		return clsBraceOpen
+	case g.Braces && r == '}':
+		return clsBraceClose
+	case g.Groups && r == '(':
+		return clsGroupOpen
+	case g.Groups && r == ')':
+		return clsGroupClose
+	}
+	return clsOther
+}
+
+// feed - обрабатывает очередной символ входа char (одна руна или один байт некорректного UTF-8).
+func (d *decoder) feed(char []byte) error {
+	pos := d.pos
+	d.pos.index++
+	d.pos.offset += int64(len(char))
+	r, _ := utf8.DecodeRune(char)
+	c := d.classify(r)
+
+	tr := d.table[d.state][c]
+	if tr.act == actEndCount {
+		// Символ после счётчика обрабатывается как в обычном тексте.
+		if err := d.repeat(); err != nil {
+			return err
+		}
+		tr = d.table[stText][c]
+	}
+	d.state = tr.next
+
+	switch tr.act {
+	case actLiteral:
+		return d.literal(char)
+	case actEscape:
+		d.escPos = pos
+		return nil
+	case actCount, actBrace:
+		// Счётчик должен следовать за символом или группой.
+		switch {
+		case d.counted:
+			return fail(pos, ReasonAdjacentDigits)
+		case len(d.prev) == 0:
+			return fail(pos, ReasonLeadingDigit)
+		}
+		d.countPos = pos
+		if tr.act == actBrace {
+			return nil
+		}
+		return d.digit(r, pos)
+	case actDigit:
+		return d.digit(r, pos)
+	case actBraceEnd:
+		if d.count == 0 {
+			return fail(pos, ReasonBadBrace)
+		}
+		return d.repeat()
+	case actOpen:
+		d.groups = append(d.groups, group{pos: pos})
+		d.prev = d.prev[:0]
+		d.counted = false
+		return nil
+	case actClose:
+		return d.closeGroup(pos)
+	}
+	// actBadBrace.
+	return fail(pos, ReasonBadBrace)
+}
+
+// flush - завершает распаковку в конце входа.
+func (d *decoder) flush() error {
+	switch d.state {
+	case stEscape:
+		return fail(d.escPos, ReasonTrailingEscape)
+	case stBrace:
+		return fail(d.countPos, ReasonUnclosedBrace)
+	case stDigit, stDigits:
+		d.state = stText
+		if err := d.repeat(); err != nil {
+			return err
+		}
+	}
+	if len(d.groups) > 0 {
+		return fail(d.groups[len(d.groups)-1].pos, ReasonUnclosedGroup)
+	}
+	return nil
+}
+
+// digit - добавляет цифру к счётчику. Нулевой счётчик и счётчик с ведущим нулём недопустимы.
+func (d *decoder) digit(r rune, pos position) error {
+	if d.count == 0 && r == '0' {
+		return fail(pos, ReasonZeroCount)
+	}
+	if d.count > d.maxRun/10 {
+		return d.tooLarge(d.countPos.offset)
+	}
+	d.count = d.count*10 + int(r-'0')
+	return d.checkRun()
+}
+
+// literal - записывает символ и запоминает его для счётчика.
+func (d *decoder) literal(char []byte) error {
+	if d.opt.MaxOutput > 0 && d.written+int64(len(char)) > d.opt.MaxOutput {
+		return d.tooLarge(d.pos.offset - int64(len(char)))
+	}
+	d.prev = append(d.prev[:0], char...)
+	d.counted = false
+	d.written += int64(len(char))
+	return d.write(char)
+}
+
+// closeGroup - закрывает текущую группу: её результат записывается в объемлющую группу
+// или в w и запоминается для счётчика.
+func (d *decoder) closeGroup(pos position) error {
+	if len(d.groups) == 0 {
+		return fail(pos, ReasonUnmatchedGroup)
+	}
+	g := d.groups[len(d.groups)-1]
+	d.groups = d.groups[:len(d.groups)-1]
+	d.prev = append(d.prev[:0], g.buf...)
+	d.counted = false
+	return d.write(g.buf)
+}
+
+// write - пишет результат в текущую группу или, вне групп, в w. Размер уже учтён в written.
+func (d *decoder) write(b []byte) error {
+	if n := len(d.groups); n > 0 {
+		d.groups[n-1].buf = append(d.groups[n-1].buf, b...)
+		return nil
+	}
+	_, err := d.w.Write(b)
+	return err
+}
+
+// checkRun - проверяет ограничения для накопленного счётчика до записи серии.
+func (d *decoder) checkRun() error {
+	if d.count > d.maxRun {
+		return d.tooLarge(d.countPos.offset)
+	}
+	if d.opt.MaxOutput > 0 && d.written+int64(len(d.prev))*int64(d.count-1) > d.opt.MaxOutput {
+		return d.tooLarge(d.countPos.offset)
+	}
+	return nil
+}
+
+// tooLarge - ошибка превышения ограничений на счётчике или символе со смещением offset.
+func (d *decoder) tooLarge(offset int64) error {
+	return fmt.Errorf("%w: limit exceeded at offset %d", ErrTooLarge, offset)
+}
+
+// repeat - применяет накопленный счётчик к предыдущему символу или группе, которые уже записаны один раз.
+func (d *decoder) repeat() error {
+	for ; d.count > 1; d.count-- {
+		d.written += int64(len(d.prev))
+		if err := d.write(d.prev); err != nil {
+			return err
+		}
+	}
+	d.count = 0
+	d.prev = d.prev[:0]
+	d.counted = true
+	return nil
+}
+
+// fail - ошибка некорректной последовательности в позиции pos.
+func fail(pos position, reason Reason) error {
+	return &ParseError{Index: pos.index, Offset: pos.offset, Reason: reason}
+}
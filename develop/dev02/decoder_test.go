@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fuzzMaxOutput - ограничение результата в fuzz-тестах, чтобы вложенные счётчики не занимали память.
+const fuzzMaxOutput = 1 << 16
+
+// errRefInvalid, errRefTooLarge - ошибки эталонной распаковки.
+var (
+	errRefInvalid  = errors.New("reference: invalid string")
+	errRefTooLarge = errors.New("reference: too large")
+)
+
+// refDecoder - эталонная распаковка рекурсивным спуском по грамматике
+//
+//	seq   = { item }
+//	item  = atom [ count ]
+//	atom  = escape rune | "(" seq ")" | rune
+//	count = digit { digit } | "{" digit { digit } "}"
+//
+// без автомата, потоковой обработки и учёта позиций ошибок. Работает на рунах, поэтому
+// сравнивается с декодером только на корректном UTF-8.
+type refDecoder struct {
+	in  []rune
+	i   int
+	opt UnpackOptions
+}
+
+// refUnpack - распаковывает str эталонным декодером с параметрами opt.
+func refUnpack(str string, opt UnpackOptions) (string, error) {
+	d := &refDecoder{in: []rune(str), opt: opt}
+	res, err := d.seq(0)
+	if err != nil {
+		return "", err
+	}
+	if d.i < len(d.in) {
+		return "", errRefInvalid // лишняя закрывающая скобка
+	}
+	return res, nil
+}
+
+// seq - последовательность элементов до конца входа или закрывающей скобки группы.
+func (d *refDecoder) seq(depth int) (string, error) {
+	var out strings.Builder
+	for d.i < len(d.in) {
+		if d.opt.Grammar.Groups && d.in[d.i] == ')' {
+			if depth == 0 {
+				return "", errRefInvalid
+			}
+			return out.String(), nil
+		}
+		atom, err := d.atom(depth)
+		if err != nil {
+			return "", err
+		}
+		n, counted, err := d.count()
+		if err != nil {
+			return "", err
+		}
+		if atom == "" && counted {
+			return "", errRefInvalid // счётчику после пустой группы нечего повторять
+		}
+		if out.Len()+len(atom)*n > fuzzMaxOutput {
+			return "", errRefTooLarge
+		}
+		out.WriteString(strings.Repeat(atom, n))
+	}
+	if depth > 0 {
+		return "", errRefInvalid
+	}
+	return out.String(), nil
+}
+
+// atom - символ, экранированный символ или группа.
+func (d *refDecoder) atom(depth int) (string, error) {
+	g := d.opt.Grammar
+	r := d.in[d.i]
+	d.i++
+	switch {
+	case r == g.escape():
+		if d.i == len(d.in) {
+			return "", errRefInvalid
+		}
+		d.i++
+		return string(d.in[d.i-1]), nil
+	case g.Groups && r == '(':
+		res, err := d.seq(depth + 1)
+		if err != nil {
+			return "", err
+		}
+		if d.i == len(d.in) {
+			return "", errRefInvalid
+		}
+		d.i++ // )
+		return res, nil
+	case isDigit(r), g.Braces && (r == '{' || r == '}'):
+		return "", errRefInvalid
+	}
+	return string(r), nil
+}
+
+// count - необязательный счётчик после атома. Без счётчика атом записывается один раз.
+func (d *refDecoder) count() (int, bool, error) {
+	if d.i == len(d.in) {
+		return 1, false, nil
+	}
+	var digits []rune
+	switch r := d.in[d.i]; {
+	case isDigit(r):
+		digits = []rune{r}
+		d.i++
+		for d.opt.MultiDigit && d.i < len(d.in) && isDigit(d.in[d.i]) {
+			digits = append(digits, d.in[d.i])
+			d.i++
+		}
+	case d.opt.Grammar.Braces && r == '{':
+		d.i++
+		for d.i < len(d.in) && isDigit(d.in[d.i]) {
+			digits = append(digits, d.in[d.i])
+			d.i++
+		}
+		if d.i == len(d.in) || d.in[d.i] != '}' || len(digits) == 0 {
+			return 0, false, errRefInvalid
+		}
+		d.i++
+	default:
+		return 1, false, nil
+	}
+	if digits[0] == '0' {
+		return 0, false, errRefInvalid
+	}
+	if len(digits) > 6 {
+		return 0, false, errRefTooLarge
+	}
+	n := 0
+	for _, r := range digits {
+		n = n*10 + int(r-'0')
+	}
+	if n > d.opt.maxRun() {
+		return 0, false, errRefTooLarge
+	}
+	return n, true, nil
+}
+
+// checkDecoder - сравнивает UnpackStrOptions и Unpacker с эталоном на строке str.
+func checkDecoder(t *testing.T, str string, opt UnpackOptions) {
+	opt.MaxOutput = fuzzMaxOutput
+	res, err := UnpackStrOptions(str, opt)
+
+	// Потоковая распаковка даёт тот же результат и ту же ошибку.
+	var out bytes.Buffer
+	serr := NewUnpackerOptions(strings.NewReader(str), opt).Unpack(&out)
+	if err == nil {
+		require.NoError(t, serr)
+		require.Equal(t, res, out.String())
+	} else {
+		require.Equal(t, err.Error(), serr.Error())
+	}
+
+	if !utf8.ValidString(str) {
+		return
+	}
+	want, rerr := refUnpack(str, opt)
+	if errors.Is(err, ErrTooLarge) || errors.Is(rerr, errRefTooLarge) {
+		return
+	}
+	if rerr != nil {
+		var perr *ParseError
+		require.ErrorAs(t, err, &perr, "reference rejects %q", str)
+		return
+	}
+	require.NoError(t, err, "reference accepts %q", str)
+	require.Equal(t, want, res)
+}
+
+func TestReference(t *testing.T) {
+	for _, test := range upsTests {
+		res, err := refUnpack(test.shortStr, UnpackOptions{})
+		require.Equal(t, test.expected.longStr, res, test.name)
+		require.Equal(t, test.expected.err != nil, err != nil, test.name)
+	}
+}
+
+func FuzzUnpackStr(f *testing.F) {
+	for _, test := range upsTests {
+		f.Add(test.shortStr, false, false, false)
+	}
+	for _, test := range grammarTests {
+		f.Add(test.shortStr, true, test.grammar.Braces, test.grammar.Groups)
+	}
+	f.Add("a\xff2(b\xc3)3", false, false, true)
+	f.Fuzz(func(t *testing.T, str string, multiDigit, braces, groups bool) {
+		opt := UnpackOptions{MultiDigit: multiDigit, Grammar: Grammar{Braces: braces, Groups: groups}}
+		checkDecoder(t, str, opt)
+	})
+}
+
+func FuzzPackStr(f *testing.F) {
+	for _, test := range packTests {
+		f.Add(test.longStr)
+	}
+	f.Fuzz(func(t *testing.T, str string) {
+		res, err := UnpackStr(PackStr(str))
+		require.NoError(t, err)
+		require.Equal(t, str, res)
+	})
+}
@@ -19,7 +19,7 @@ package main
 
 import (
 	"fmt"
-	"strconv"
+	"os"
 	"strings"
 	"unicode/utf8"
 )
@@ -28,83 +28,51 @@ import (
 var ErrInvalid = fmt.Errorf("invalid string")
 
 // UnpackStr - Go-функция, осуществляющая примитивную распаковку строки, содержащую повторяющиеся символы/руны.
+// Цифра после символа задаёт число его повторений, обратный слэш экранирует следующий символ
+// (в том числе цифру и сам обратный слэш). Строка, начинающаяся с цифры, две цифры подряд,
+// нулевой счётчик и обратный слэш в конце строки считаются некорректными: в этом случае возвращается
+// *ParseError с позицией и причиной ошибки, для которой errors.Is(err, ErrInvalid) истинно.
 func UnpackStr(str string) (string, error) {
-	// Если строка пустая, то вернуть пустую строку без ошибки.
-	if len(str) == 0 {
-		return "", nil
-	}
+	return UnpackStrOptions(str, UnpackOptions{})
+}
 
-	firstRun, width := utf8.DecodeRuneInString(str)
-	// Если первый символ - число, то вернуть ошибку и пустую строку.
-	// Если есть только символ обратного слэша, то вернуть ошибку и пустую строку.
-	if firstRun >= '0' && firstRun <= '9' || len(str) == width && firstRun == '\\' {
-		return "", ErrInvalid
-	}
+// UnpackStrN - вариант UnpackStr с многозначными счётчиками: "a12" распаковывается в двенадцать символов a.
+// Экранированные цифры остаются символами, как и в UnpackStr. Счётчик с ведущим нулём некорректен,
+// счётчик больше maxCount (при maxCount <= 0 - больше DefaultMaxCount) - ошибка ErrTooLarge.
+func UnpackStrN(str string, maxCount int) (string, error) {
+	return UnpackStrOptions(str, UnpackOptions{MultiDigit: true, MaxRun: maxCount})
+}
 
-	w := width
-	backSlash := false
-	printBackSlash := false
-	retStr := ""
-	// Проверка на обратный слэш. Если его нет в считанном значении, то символ добавляется в строку.
-	if firstRun != '\\' {
-		retStr = concat(retStr, string(firstRun))
-	}
-	if firstRun == '\\' {
-		backSlash = true
-		firstRun, width = utf8.DecodeRuneInString(str[w:])
-		w += width
-		retStr = concat(retStr, string(firstRun))
+// UnpackStrOptions - распаковка с параметрами opt. При превышении ограничений возвращает ошибку
+// ErrTooLarge, не выделяя память под результат сверх ограничения, при некорректной грамматике - ErrGrammar.
+func UnpackStrOptions(str string, opt UnpackOptions) (string, error) {
+	if err := opt.Grammar.validate(); err != nil {
+		return "", err
 	}
+	var builder strings.Builder
+	builder.Grow(len(str))
+	d := newDecoder(&builder, opt)
 
-	// Цикл считывания переданной строки.
-	for i, w := w, 0; i < len(str); i += w {
-		secondRun, width := utf8.DecodeRuneInString(str[i:])
-		w = width
-
-		// Проверка на наличие лишнего \ в конце строки, на наличие нулевого счётчика символов и повторения чисел без \.
-		if !backSlash && !printBackSlash && secondRun == '\\' && i+w == len(str) || firstRun != '\\' && secondRun == 0 ||
-			!backSlash && secondRun >= '0' && secondRun <= '9' && firstRun >= '0' && firstRun <= '9' {
-			return "", ErrInvalid
-		} else if !backSlash && secondRun == '\\' {
-			backSlash = true
-		} else if backSlash && firstRun == '\\' && !printBackSlash {
-			retStr = concat(retStr, string(secondRun))
-			if secondRun == '\\' {
-				printBackSlash = true
-			}
-		} else if secondRun >= '0' && secondRun <= '9' {
-			k, _ := strconv.Atoi(string(secondRun))
-			for i := 0; i < k-1; i++ {
-				retStr = concat(retStr, string(firstRun))
-			}
-			if firstRun == '\\' {
-				printBackSlash = false
-			}
-			backSlash = false
-		} else if backSlash && printBackSlash {
-			printBackSlash = false
-		} else if backSlash && secondRun == '\\' {
-		} else {
-			backSlash = false
-			retStr = concat(retStr, string(secondRun))
-		}
-		if printBackSlash && secondRun == '\\' && i+w == len(str) {
-			return "", ErrInvalid
+	// Символы передаются байтами, поэтому некорректные последовательности UTF-8 сохраняются как есть.
+	var buf [utf8.UTFMax]byte
+	for i := 0; i < len(str); {
+		_, w := utf8.DecodeRuneInString(str[i:])
+		if err := d.feed(append(buf[:0], str[i:i+w]...)); err != nil {
+			return "", err
 		}
-		firstRun = secondRun
+		i += w
 	}
-	return retStr, nil
+	if err := d.flush(); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
 }
 
-// concat выполняет эффективную конкатенацию строк
-func concat(x, y string) string {
-	var builder strings.Builder
-	builder.Grow(len(x) + len(y)) // Эта строка выделяет память
-	builder.WriteString(x)        //Записывает в builder строку.
-	builder.WriteString(y)
-	return builder.String()
+// isDigit - является ли руна десятичной цифрой счётчика.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
 }
 
 func main() {
-	fmt.Println(UnpackStr(`qw\\\e`))
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -153,6 +155,38 @@ var upsTests = []upsTest{
 			err:     ErrInvalid,
 		},
 	},
+	{
+		name:     "test18",
+		shortStr: `a\\b`,
+		expected: expected{
+			longStr: `a\b`,
+			err:     nil,
+		},
+	},
+	{
+		name:     "test19",
+		shortStr: `\\3`,
+		expected: expected{
+			longStr: `\\\`,
+			err:     nil,
+		},
+	},
+	{
+		name:     "test20",
+		shortStr: "a0",
+		expected: expected{
+			longStr: "",
+			err:     ErrInvalid,
+		},
+	},
+	{
+		name:     "test21",
+		shortStr: "a12",
+		expected: expected{
+			longStr: "",
+			err:     ErrInvalid,
+		},
+	},
 }
 
 func TestUnpackStr(t *testing.T) {
@@ -160,7 +194,70 @@ func TestUnpackStr(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			res, err := UnpackStr(test.shortStr)
 			require.Equal(t, test.expected.longStr, res)
-			require.Equal(t, test.expected.err, err)
+			if test.expected.err != nil {
+				require.ErrorIs(t, err, test.expected.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+var upsnTests = []struct {
+	name, shortStr string
+	maxCount       int
+	expected       expected
+}{
+	{name: "test1", shortStr: "a12b", expected: expected{longStr: "aaaaaaaaaaaab"}},
+	{name: "test2", shortStr: "a4bc2d5e", expected: expected{longStr: "aaaabccddddde"}},
+	{name: "test3", shortStr: `\12`, expected: expected{longStr: "11"}},
+	{name: "test4", shortStr: `a\12`, expected: expected{longStr: "a11"}},
+	{name: "test5", shortStr: `\\10`, expected: expected{longStr: strings.Repeat(`\`, 10)}},
+	{name: "test6", shortStr: "12a", expected: expected{err: ErrInvalid}},
+	{name: "test7", shortStr: "a0", expected: expected{err: ErrInvalid}},
+	{name: "test8", shortStr: "a05", expected: expected{err: ErrInvalid}},
+	{name: "test9", shortStr: "a10", maxCount: 9, expected: expected{err: ErrTooLarge}},
+	{name: "test10", shortStr: "a9", maxCount: 9, expected: expected{longStr: "aaaaaaaaa"}},
+	{name: "test11", shortStr: "a99999999999999999999999", expected: expected{err: ErrTooLarge}},
+	{name: "test12", shortStr: `a12\`, expected: expected{err: ErrInvalid}},
+}
+
+func TestUnpackStrN(t *testing.T) {
+	for _, test := range upsnTests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := UnpackStrN(test.shortStr, test.maxCount)
+			require.Equal(t, test.expected.longStr, res)
+			if test.expected.err != nil {
+				require.ErrorIs(t, err, test.expected.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+var reasonTests = []struct {
+	name, shortStr string
+	expected       ParseError
+}{
+	{name: "test1", shortStr: "45", expected: ParseError{Index: 0, Offset: 0, Reason: ReasonLeadingDigit}},
+	{name: "test2", shortStr: `😂\`, expected: ParseError{Index: 1, Offset: 4, Reason: ReasonTrailingEscape}},
+	{name: "test3", shortStr: "⌘b0", expected: ParseError{Index: 2, Offset: 4, Reason: ReasonZeroCount}},
+	{name: "test4", shortStr: "ab12", expected: ParseError{Index: 3, Offset: 3, Reason: ReasonAdjacentDigits}},
+	{name: "test5", shortStr: `qwe\\5\`, expected: ParseError{Index: 6, Offset: 6, Reason: ReasonTrailingEscape}},
+}
+
+func TestUnpackStrReason(t *testing.T) {
+	for _, test := range reasonTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := UnpackStr(test.shortStr)
+			var perr *ParseError
+			require.ErrorAs(t, err, &perr)
+			require.Equal(t, test.expected, *perr)
+			require.True(t, errors.Is(err, ErrInvalid))
 		})
 	}
+
+	_, err := UnpackStr("ab12")
+	require.EqualError(t, err, "invalid string: adjacent digits at offset 3 (rune 3)")
 }
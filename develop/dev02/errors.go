@@ -0,0 +1,59 @@
+package main
+
+import "fmt"
+
+// Reason - причина, по которой упакованная строка некорректна.
+type Reason int
+
+// Причины ошибок распаковки.
+const (
+	ReasonLeadingDigit   Reason = iota + 1 // строка начинается со счётчика
+	ReasonTrailingEscape                   // обратный слэш в конце строки
+	ReasonZeroCount                        // нулевой счётчик или счётчик с ведущим нулём
+	ReasonAdjacentDigits                   // две цифры подряд без экранирования
+	ReasonBadBrace                         // пустой счётчик или не цифра в фигурных скобках
+	ReasonUnclosedBrace                    // незакрытая фигурная скобка в конце строки
+	ReasonUnmatchedGroup                   // закрывающая круглая скобка без открывающей
+	ReasonUnclosedGroup                    // незакрытая группа в конце строки
+)
+
+// String - описание причины для сообщения об ошибке.
+func (r Reason) String() string {
+	switch r {
+	case ReasonLeadingDigit:
+		return "leading digit"
+	case ReasonTrailingEscape:
+		return "trailing escape"
+	case ReasonZeroCount:
+		return "zero count"
+	case ReasonAdjacentDigits:
+		return "adjacent digits"
+	case ReasonBadBrace:
+		return "invalid brace count"
+	case ReasonUnclosedBrace:
+		return "unclosed brace"
+	case ReasonUnmatchedGroup:
+		return "unmatched parenthesis"
+	case ReasonUnclosedGroup:
+		return "unclosed group"
+	}
+	return fmt.Sprintf("reason %d", int(r))
+}
+
+// ParseError - ошибка распаковки с позицией первой некорректной последовательности.
+// errors.Is(err, ErrInvalid) для неё истинно, поэтому прежние проверки продолжают работать.
+type ParseError struct {
+	Index  int   // номер символа (руны) от начала строки
+	Offset int64 // смещение в байтах от начала строки
+	Reason Reason
+}
+
+// Error - текст ошибки.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s at offset %d (rune %d)", ErrInvalid, e.Reason, e.Offset, e.Index)
+}
+
+// Is - ParseError считается ошибкой ErrInvalid.
+func (e *ParseError) Is(target error) bool {
+	return target == ErrInvalid
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrGrammar - ошибка некорректной настройки грамматики.
+var ErrGrammar = errors.New("invalid grammar")
+
+// Grammar - синтаксис упакованной строки. Нулевое значение - синтаксис UnpackStr:
+// обратный слэш экранирует следующий символ, счётчик записывается цифрами после символа.
+type Grammar struct {
+	// Escape - экранирующий символ, 0 - обратный слэш.
+	Escape rune
+	// Braces - счётчик из нескольких цифр в фигурных скобках: a{12}. Фигурные скобки
+	// становятся служебными символами и экранируются для записи как есть.
+	Braces bool
+	// Groups - группировка в круглых скобках: (ab)3 - ababab. Группы могут быть вложенными,
+	// круглые скобки становятся служебными символами и экранируются для записи как есть.
+	Groups bool
+}
+
+// escape - действующий экранирующий символ.
+func (g Grammar) escape() rune {
+	if g.Escape == 0 {
+		return '\\'
+	}
+	return g.Escape
+}
+
+// validate - экранирующий символ не может быть цифрой или служебным символом включённых конструкций.
+func (g Grammar) validate() error {
+	esc := g.escape()
+	switch {
+	case isDigit(esc),
+		g.Braces && (esc == '{' || esc == '}'),
+		g.Groups && (esc == '(' || esc == ')'):
+		return fmt.Errorf("%w: escape %q conflicts with count syntax", ErrGrammar, esc)
+	}
+	return nil
+}
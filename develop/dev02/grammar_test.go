@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var grammarTests = []struct {
+	name, shortStr string
+	grammar        Grammar
+	expected       expected
+}{
+	{name: "test1", shortStr: "a4bc2d5e", grammar: Grammar{Braces: true, Groups: true}, expected: expected{longStr: "aaaabccddddde"}},
+	{name: "test2", shortStr: "a{12}b", grammar: Grammar{Braces: true}, expected: expected{longStr: strings.Repeat("a", 12) + "b"}},
+	{name: "test3", shortStr: `\{a\}{3}`, grammar: Grammar{Braces: true}, expected: expected{longStr: "{a}}}"}},
+	{name: "test4", shortStr: "a{}", grammar: Grammar{Braces: true}, expected: expected{err: ErrInvalid}},
+	{name: "test5", shortStr: "a{1x}", grammar: Grammar{Braces: true}, expected: expected{err: ErrInvalid}},
+	{name: "test6", shortStr: "a{12", grammar: Grammar{Braces: true}, expected: expected{err: ErrInvalid}},
+	{name: "test7", shortStr: "a{0}", grammar: Grammar{Braces: true}, expected: expected{err: ErrInvalid}},
+	{name: "test8", shortStr: "a{2}3", grammar: Grammar{Braces: true}, expected: expected{err: ErrInvalid}},
+	{name: "test9", shortStr: "a}", grammar: Grammar{Braces: true}, expected: expected{err: ErrInvalid}},
+	{name: "test10", shortStr: "a{12}", expected: expected{err: ErrInvalid}},
+	{name: "test11", shortStr: "(ab)3", grammar: Grammar{Groups: true}, expected: expected{longStr: "ababab"}},
+	{name: "test12", shortStr: "x(a(bc)2)2y", grammar: Grammar{Groups: true}, expected: expected{longStr: "xabcbcabcbcy"}},
+	{name: "test13", shortStr: "(ab){10}", grammar: Grammar{Braces: true, Groups: true}, expected: expected{longStr: strings.Repeat("ab", 10)}},
+	{name: "test14", shortStr: `(a\)2)2`, grammar: Grammar{Groups: true}, expected: expected{longStr: "a))a))"}},
+	{name: "test15", shortStr: "(ab", grammar: Grammar{Groups: true}, expected: expected{err: ErrInvalid}},
+	{name: "test16", shortStr: "ab)", grammar: Grammar{Groups: true}, expected: expected{err: ErrInvalid}},
+	{name: "test17", shortStr: "()3", grammar: Grammar{Groups: true}, expected: expected{err: ErrInvalid}},
+	{name: "test18", shortStr: "(ab)", grammar: Grammar{Groups: true}, expected: expected{longStr: "ab"}},
+	{name: "test19", shortStr: "(ab)3", expected: expected{longStr: "(ab)))"}},
+	{name: "test20", shortStr: `qwe%45%%3\`, grammar: Grammar{Escape: '%'}, expected: expected{longStr: `qwe44444%%%\`}},
+	{name: "test21", shortStr: "ab%", grammar: Grammar{Escape: '%'}, expected: expected{err: ErrInvalid}},
+	{name: "test22", shortStr: "ab", grammar: Grammar{Escape: '1'}, expected: expected{err: ErrGrammar}},
+	{name: "test23", shortStr: "ab", grammar: Grammar{Escape: '(', Groups: true}, expected: expected{err: ErrGrammar}},
+	{name: "test24", shortStr: "ab", grammar: Grammar{Escape: '{'}, expected: expected{longStr: "ab"}},
+}
+
+func TestGrammar(t *testing.T) {
+	for _, test := range grammarTests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := UnpackStrOptions(test.shortStr, UnpackOptions{Grammar: test.grammar})
+			require.Equal(t, test.expected.longStr, res)
+			if test.expected.err != nil {
+				require.ErrorIs(t, err, test.expected.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+var grammarReasonTests = []struct {
+	name, shortStr string
+	expected       ParseError
+}{
+	{name: "test1", shortStr: "a{}", expected: ParseError{Index: 2, Offset: 2, Reason: ReasonBadBrace}},
+	{name: "test2", shortStr: "ab{12", expected: ParseError{Index: 2, Offset: 2, Reason: ReasonUnclosedBrace}},
+	{name: "test3", shortStr: "a(b(c)", expected: ParseError{Index: 1, Offset: 1, Reason: ReasonUnclosedGroup}},
+	{name: "test4", shortStr: "a)", expected: ParseError{Index: 1, Offset: 1, Reason: ReasonUnmatchedGroup}},
+	{name: "test5", shortStr: "(a)2{3}", expected: ParseError{Index: 4, Offset: 4, Reason: ReasonAdjacentDigits}},
+}
+
+func TestGrammarReason(t *testing.T) {
+	opt := UnpackOptions{Grammar: Grammar{Braces: true, Groups: true}}
+	for _, test := range grammarReasonTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := UnpackStrOptions(test.shortStr, opt)
+			var perr *ParseError
+			require.ErrorAs(t, err, &perr)
+			require.Equal(t, test.expected, *perr)
+		})
+	}
+}
+
+func TestGrammarLimits(t *testing.T) {
+	opt := UnpackOptions{MaxOutput: 100, Grammar: Grammar{Braces: true, Groups: true}}
+	_, err := UnpackStrOptions("((ab)9)9", opt)
+	require.ErrorIs(t, err, ErrTooLarge)
+
+	res, err := UnpackStrOptions("((ab)5){10}", opt)
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat("ab", 50), res)
+
+	_, err = UnpackStrOptions("a{100000}", UnpackOptions{Grammar: Grammar{Braces: true}})
+	require.ErrorIs(t, err, ErrTooLarge)
+}
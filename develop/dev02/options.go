@@ -0,0 +1,34 @@
+package main
+
+import "errors"
+
+// ErrTooLarge - ошибка превышения ограничений UnpackOptions. Возвращается до записи
+// части результата, выходящей за ограничение.
+var ErrTooLarge = errors.New("unpacked string too large")
+
+// DefaultMaxCount - наибольший счётчик при многозначных счётчиках по умолчанию.
+const DefaultMaxCount = 1 << 16
+
+// UnpackOptions - параметры распаковки. Нулевое значение соответствует UnpackStr.
+type UnpackOptions struct {
+	// MultiDigit - счётчик может состоять из нескольких цифр: "a12" - двенадцать символов a.
+	MultiDigit bool
+	// MaxRun - наибольший счётчик. При MaxRun <= 0 - 9 для однозначных счётчиков
+	// и DefaultMaxCount для многозначных и счётчиков в фигурных скобках.
+	MaxRun int
+	// MaxOutput - наибольший размер результата в байтах, при MaxOutput <= 0 - без ограничения.
+	MaxOutput int64
+	// Grammar - синтаксис упакованной строки.
+	Grammar Grammar
+}
+
+// maxRun - действующее ограничение счётчика.
+func (o UnpackOptions) maxRun() int {
+	switch {
+	case o.MaxRun > 0:
+		return o.MaxRun
+	case o.MultiDigit, o.Grammar.Braces:
+		return DefaultMaxCount
+	}
+	return 9
+}
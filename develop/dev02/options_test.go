@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var optionsTests = []struct {
+	name, shortStr string
+	opt            UnpackOptions
+	expected       expected
+}{
+	{name: "test1", shortStr: "a4bc2d5e", expected: expected{longStr: "aaaabccddddde"}},
+	{name: "test2", shortStr: "a4bc2d5e", opt: UnpackOptions{MaxRun: 4}, expected: expected{err: ErrTooLarge}},
+	{name: "test3", shortStr: "a4bc2d5e", opt: UnpackOptions{MaxOutput: 13}, expected: expected{longStr: "aaaabccddddde"}},
+	{name: "test4", shortStr: "a4bc2d5e", opt: UnpackOptions{MaxOutput: 12}, expected: expected{err: ErrTooLarge}},
+	{name: "test5", shortStr: "abcd", opt: UnpackOptions{MaxOutput: 3}, expected: expected{err: ErrTooLarge}},
+	{name: "test6", shortStr: "👍4", opt: UnpackOptions{MaxOutput: 15}, expected: expected{err: ErrTooLarge}},
+	{name: "test7", shortStr: "a12", opt: UnpackOptions{MultiDigit: true}, expected: expected{longStr: strings.Repeat("a", 12)}},
+	{name: "test8", shortStr: "a70000", opt: UnpackOptions{MultiDigit: true}, expected: expected{err: ErrTooLarge}},
+	{name: "test9", shortStr: "a999999999999999999999", opt: UnpackOptions{MultiDigit: true, MaxRun: math.MaxInt}, expected: expected{err: ErrTooLarge}},
+	{name: "test10", shortStr: "a1000000000", opt: UnpackOptions{MultiDigit: true, MaxRun: math.MaxInt, MaxOutput: 1 << 20}, expected: expected{err: ErrTooLarge}},
+	{name: "test11", shortStr: "45", opt: UnpackOptions{MaxOutput: 1}, expected: expected{err: ErrInvalid}},
+}
+
+func TestUnpackStrOptions(t *testing.T) {
+	for _, test := range optionsTests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := UnpackStrOptions(test.shortStr, test.opt)
+			require.Equal(t, test.expected.longStr, res)
+			if test.expected.err != nil {
+				require.ErrorIs(t, err, test.expected.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUnpackerOptions(t *testing.T) {
+	// Распаковка останавливается на первой серии, выходящей за ограничение.
+	in := strings.Repeat("x9", 1000)
+	var out bytes.Buffer
+	u := NewUnpackerOptions(strings.NewReader(in), UnpackOptions{MaxOutput: 100})
+	require.ErrorIs(t, u.Unpack(&out), ErrTooLarge)
+	require.Equal(t, strings.Repeat("x", 100), out.String())
+	require.Equal(t, int64(-1), u.Offset())
+}
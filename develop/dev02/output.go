@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// atomicFile - файл результата pack/unpack -o: результат пишется во временный файл в том же
+// каталоге, который заменяет name только в commit. unpack читает вход потоком одновременно с записью,
+// поэтому без временного файла -o, совпадающий со входом, обрезал бы его до чтения, а ошибка
+// разбора посреди входа оставляла бы name наполовину записанным.
+type atomicFile struct {
+	*os.File
+	name      string
+	committed bool
+}
+
+// createAtomic - создаёт временный файл для результата name. Права доступа берутся
+// у существующего файла name, для нового файла - 0644.
+func createAtomic(name string) (*atomicFile, error) {
+	perm := os.FileMode(0o644)
+	if fi, err := os.Stat(name); err == nil {
+		if !fi.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s: not a regular file", name)
+		}
+		perm = fi.Mode().Perm()
+		// Заменяется файл, на который указывает ссылка, а не сама ссылка.
+		if name, err = filepath.EvalSymlinks(name); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &atomicFile{File: f, name: name}, nil
+}
+
+// commit - закрывает временный файл и переименовывает его в name.
+func (a *atomicFile) commit() error {
+	if err := a.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(a.Name(), a.name); err != nil {
+		return err
+	}
+	a.committed = true
+	return nil
+}
+
+// abort - удаляет временный файл, если результат не был сохранён.
+func (a *atomicFile) abort() {
+	if !a.committed {
+		a.Close()
+		os.Remove(a.Name())
+	}
+}
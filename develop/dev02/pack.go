@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCount - наибольшее число повторений, которое записывается одной цифрой.
+const maxCount = 9
+
+// PackStr - Go-функция, обратная UnpackStr: сжимает повторяющиеся символы/руны в кратчайшую строку,
+// которую UnpackStr распакует обратно в str. Цифры и обратный слэш экранируются, серии длиннее
+// maxCount разбиваются на несколько. Некорректные последовательности UTF-8 сохраняются побайтно.
+func PackStr(str string) string {
+	var builder strings.Builder
+	builder.Grow(len(str))
+
+	for i := 0; i < len(str); {
+		_, w := utf8.DecodeRuneInString(str[i:])
+		char := str[i : i+w]
+		n := 1
+		for strings.HasPrefix(str[i+n*w:], char) {
+			n++
+		}
+		i += n * w
+		writeRun(&builder, char, n)
+	}
+	return builder.String()
+}
+
+// writeRun - записывает серию из n символов char. Серия из одного символа записывается без счётчика.
+func writeRun(builder *strings.Builder, char string, n int) {
+	for n > 0 {
+		count := n
+		if count > maxCount {
+			count = maxCount
+		}
+		if char == `\` || char[0] >= '0' && char[0] <= '9' {
+			builder.WriteByte('\\')
+		}
+		builder.WriteString(char)
+		if count > 1 {
+			builder.WriteByte(byte('0' + count))
+		}
+		n -= count
+	}
+}
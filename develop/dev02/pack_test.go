@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/require"
+)
+
+var packTests = []struct {
+	name, longStr, shortStr string
+}{
+	{name: "test1", longStr: "aaaabccddddde", shortStr: "a4bc2d5e"},
+	{name: "test2", longStr: "abcd", shortStr: "abcd"},
+	{name: "test3", longStr: "", shortStr: ""},
+	{name: "test4", longStr: "😂⌘👍👍👍👍", shortStr: "😂⌘👍4"},
+	{name: "test5", longStr: "qwe45", shortStr: `qwe\4\5`},
+	{name: "test6", longStr: "qwe44444", shortStr: `qwe\45`},
+	{name: "test7", longStr: `qwe\\\\\`, shortStr: `qwe\\5`},
+	{name: "test8", longStr: strings.Repeat("a", 10), shortStr: "a9a"},
+	{name: "test9", longStr: strings.Repeat("a", 20), shortStr: "a9a9a2"},
+	{name: "test10", longStr: "\xff\xff\xff", shortStr: "\xff3"},
+}
+
+func TestPackStr(t *testing.T) {
+	for _, test := range packTests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.shortStr, PackStr(test.longStr))
+		})
+	}
+}
+
+// escapeStr - упаковка без счётчиков: только экранирование цифр и обратного слэша.
+func escapeStr(str string) string {
+	var builder strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' || str[i] >= '0' && str[i] <= '9' {
+			builder.WriteByte('\\')
+		}
+		builder.WriteByte(str[i])
+	}
+	return builder.String()
+}
+
+func TestPackStrRoundTrip(t *testing.T) {
+	roundTrip := func(str string) bool {
+		res, err := UnpackStr(PackStr(str))
+		return err == nil && res == str
+	}
+	require.NoError(t, quick.Check(roundTrip, nil))
+
+	// Строки из произвольных байт, в том числе некорректный UTF-8.
+	require.NoError(t, quick.Check(func(b []byte) bool { return roundTrip(string(b)) }, nil))
+
+	// Строки с длинными сериями цифр и обратных слэшей.
+	require.NoError(t, quick.Check(func(runs []uint8) bool {
+		var builder strings.Builder
+		for i, n := range runs {
+			builder.WriteString(strings.Repeat(string(`\1a`[i%3]), int(n%30)))
+		}
+		return roundTrip(builder.String())
+	}, nil))
+
+	// Упакованная строка не длиннее простого экранирования.
+	require.NoError(t, quick.Check(func(str string) bool {
+		return len(PackStr(str)) <= len(escapeStr(str))
+	}, nil))
+}
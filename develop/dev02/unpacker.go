@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// Unpacker - потоковая распаковка: читает руны из io.Reader и пишет результат в io.Writer,
+// не загружая вход в память целиком. Правила распаковки те же, что у UnpackStr.
+// В памяти хранится только содержимое открытых групп (Grammar.Groups).
+type Unpacker struct {
+	r      *bufio.Reader
+	opt    UnpackOptions
+	offset int64
+}
+
+// NewUnpacker - создаёт Unpacker, читающий упакованную строку из r.
+func NewUnpacker(r io.Reader) *Unpacker {
+	return NewUnpackerOptions(r, UnpackOptions{})
+}
+
+// NewUnpackerOptions - создаёт Unpacker с параметрами распаковки opt.
+func NewUnpackerOptions(r io.Reader, opt UnpackOptions) *Unpacker {
+	return &Unpacker{r: bufio.NewReader(r), opt: opt, offset: -1}
+}
+
+// Unpack - распаковывает поток до конца и пишет результат в w. При некорректной строке возвращает
+// *ParseError, а Offset - смещение её начала; распакованная до ошибки часть к этому моменту уже записана в w.
+func (u *Unpacker) Unpack(w io.Writer) error {
+	if err := u.opt.Grammar.validate(); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	err := u.decode(newDecoder(bw, u.opt))
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		u.offset = perr.Offset
+	}
+	if ferr := bw.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// Offset - смещение в байтах первой некорректной последовательности или -1, если её не было.
+func (u *Unpacker) Offset() int64 {
+	return u.offset
+}
+
+// decode - передаёт декодеру символы из входного потока.
+func (u *Unpacker) decode(d *decoder) error {
+	var buf [utf8.UTFMax]byte
+	for {
+		r, size, err := u.r.ReadRune()
+		if err == io.EOF {
+			return d.flush()
+		}
+		if err != nil {
+			return err
+		}
+		char := buf[:utf8.EncodeRune(buf[:], r)]
+		if r == utf8.RuneError && size == 1 {
+			// Некорректный UTF-8 передаётся исходным байтом, а не заменяющим символом.
+			u.r.UnreadRune()
+			b, _ := u.r.ReadByte()
+			char = append(buf[:0], b)
+		}
+		if err := d.feed(char); err != nil {
+			return err
+		}
+	}
+}
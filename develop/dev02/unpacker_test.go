@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpacker(t *testing.T) {
+	for _, test := range upsTests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			u := NewUnpacker(strings.NewReader(test.shortStr))
+			err := u.Unpack(&out)
+			if test.expected.err != nil {
+				require.ErrorIs(t, err, test.expected.err)
+			} else {
+				require.Equal(t, test.expected.longStr, out.String())
+				require.Equal(t, int64(-1), u.Offset())
+			}
+		})
+	}
+}
+
+var offsetTests = []struct {
+	name, shortStr string
+	offset         int64
+}{
+	{name: "test1", shortStr: "45", offset: 0},
+	{name: "test2", shortStr: "ab12", offset: 3},
+	{name: "test3", shortStr: "😂0", offset: 4},
+	{name: "test4", shortStr: `qwe\\5\`, offset: 6},
+	{name: "test5", shortStr: `ab\`, offset: 2},
+	{name: "test6", shortStr: "a2\xff33b", offset: 4},
+}
+
+func TestUnpackerOffset(t *testing.T) {
+	for _, test := range offsetTests {
+		t.Run(test.name, func(t *testing.T) {
+			u := NewUnpacker(strings.NewReader(test.shortStr))
+			require.ErrorIs(t, u.Unpack(&bytes.Buffer{}), ErrInvalid)
+			require.Equal(t, test.offset, u.Offset())
+		})
+	}
+}
+
+func TestUnpackerStream(t *testing.T) {
+	// Вход читается по одному байту, руны и счётчики разбиты между чтениями.
+	in := strings.Repeat("😂⌘\\45\xffx9", 1000)
+	want, err := UnpackStr(in)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	u := NewUnpacker(iotest.OneByteReader(strings.NewReader(in)))
+	require.NoError(t, u.Unpack(&out))
+	require.Equal(t, want, out.String())
+
+	errRead := errors.New("read failed")
+	u = NewUnpacker(iotest.ErrReader(errRead))
+	require.Equal(t, errRead, u.Unpack(&out))
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Коды выхода программы, как у sort: 1 - данные не отсортированы (-c), 2 - ошибка.
+const (
+	exitOK       = 0
+	exitDisorder = 1
+	exitError    = 2
+)
+
+// run - разбирает флаги и сортирует входы. Без файлов или с файлом "-" читается stdin,
+// несколько файлов сортируются как один поток. Результат пишется в stdout или в файл -o.
+// Ошибки выводятся в stderr, возвращаемое значение - код выхода для ОС.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dev03", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var fl SortingFlags
+	fs.Var((*keyList)(&fl.keys), "k", "ключ сортировки POS1[,POS2][OPTS] (-k 2,2n или -k2,2n), где POS - F[.C], OPTS - модификаторы n, r, M, h, V, b; можно указать несколько раз")
+	fs.BoolVar(&fl.num, "n", false, "сортировать по числовому значению")
+	fs.BoolVar(&fl.reverse, "r", false, "сортировать в обратном порядке")
+	fs.BoolVar(&fl.month, "M", false, "сортировать по названию месяца (английскому или русскому)")
+	fs.BoolVar(&fl.human, "h", false, "сортировать по числовому значению с учетом суффиксов K, M, G, T")
+	fs.BoolVar(&fl.version, "V", false, "естественная сортировка: числа внутри текста сравниваются как числа (file2 < file10)")
+	collate := fs.String("collate", "", "сравнивать текст по правилам Unicode Collation Algorithm для языка: en или ru; по умолчанию - побайтово без учёта регистра")
+	fs.BoolVar(&fl.blanks, "b", false, "игнорировать пробелы в начале ключа")
+	check := fs.Bool("c", false, "проверить, отсортированы ли данные, и сообщить о первой неупорядоченной строке")
+	fs.BoolVar(&fl.unique, "u", false, "убрать строки, равные по ключам сортировки предыдущей: из равных строк остаётся первая")
+	fs.BoolVar(&fl.count, "count", false, "как -u, но перед каждой строкой вывести число равных ей строк, как uniq -c")
+	fs.StringVar(&fl.sep, "t", "", `разделитель полей (\t - табуляция); по умолчанию поля разделяются сериями пробелов`)
+	fs.IntVar(&fl.parallel, "parallel", 1, "число потоков сортировки, 0 - по числу ядер процессора")
+	bufSize := fs.String("S", "", "размер буфера внешней сортировки: число с суффиксом b, K, M, G, T (по умолчанию K); без флага данные сортируются в памяти")
+	fs.StringVar(&fl.tempDir, "T", "", "каталог для временных файлов внешней сортировки")
+	merge := fs.Bool("m", false, "слить уже отсортированные входы без пересортировки")
+	checkOrder := fs.Bool("check-order", false, "при -m проверять, что каждый вход отсортирован")
+	output := fs.String("o", "", "файл для результата, по умолчанию stdout; может совпадать с одним из входов")
+	if err := fs.Parse(splitAttached(fs, args)); err != nil {
+		return exitError
+	}
+	if fl.count {
+		fl.unique = true
+	}
+	if fl.sep == `\t` {
+		fl.sep = "\t"
+	}
+	if *collate != "" {
+		col, err := newCollator(*collate)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return exitError
+		}
+		fl.collate = col
+	}
+	if *bufSize != "" {
+		size, err := parseSize(*bufSize)
+		if err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return exitError
+		}
+		fl.bufSize = size
+	}
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{"-"}
+	}
+	if *check && *merge {
+		fmt.Fprintln(stderr, "options -c and -m are incompatible")
+		return exitError
+	}
+	if *checkOrder && !*merge {
+		fmt.Fprintln(stderr, "option -check-order requires -m")
+		return exitError
+	}
+	if *check && len(names) > 1 {
+		fmt.Fprintf(stderr, "extra operand %q not allowed with -c\n", names[1])
+		return exitError
+	}
+
+	inputs, closeInputs, err := openInputs(names, stdin)
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return exitError
+	}
+	defer closeInputs()
+	r := io.MultiReader(inputs...)
+
+	if *check {
+		n, line, err := checkSorted(r, &fl)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", displayName(names[0]), err)
+			return exitError
+		}
+		if n > 0 {
+			fmt.Fprintf(stderr, "%s:%d: disorder: %s\n", displayName(names[0]), n, line)
+			return exitDisorder
+		}
+		return exitOK
+	}
+
+	var out *atomicFile
+	w := bufio.NewWriter(stdout)
+	if *output != "" {
+		if out, err = createAtomic(*output); err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			return exitError
+		}
+		defer out.abort()
+		w = bufio.NewWriter(out)
+	}
+	switch {
+	case *merge:
+		err = mergeSorted(inputs, names, w, &fl, *checkOrder)
+	case fl.bufSize > 0:
+		err = externalSort(r, w, &fl)
+	default:
+		var msg []string
+		if msg, err = fileRead(r); err == nil {
+			_, err = w.Write(sortFile(msg, &fl))
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil && out != nil {
+		err = out.commit()
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err.Error())
+		return exitError
+	}
+	return exitOK
+}
+
+// splitAttached - разделяет слитную форму однобуквенных флагов со значением, как в sort:
+// -k2,2n и -t: превращаются в -k 2,2n и -t :. Пакет flag понимает только раздельную форму.
+func splitAttached(fs *flag.FlagSet, args []string) []string {
+	ret := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			// Разбор флагов заканчивается на первом аргументе, который не является флагом.
+			return append(ret, args[i:]...)
+		}
+		name := strings.TrimLeft(arg, "-")
+		if j := strings.IndexByte(name, '='); j >= 0 {
+			name = name[:j]
+		}
+		if f := fs.Lookup(name); f != nil {
+			ret = append(ret, arg)
+			if !isBoolFlag(f) && !strings.Contains(arg, "=") && i+1 < len(args) {
+				// Значение флага передаётся следующим аргументом как есть.
+				i++
+				ret = append(ret, args[i])
+			}
+			continue
+		}
+		if f := fs.Lookup(arg[1:2]); f != nil && !isBoolFlag(f) && arg[1] != '-' {
+			ret = append(ret, arg[:2], arg[2:])
+			continue
+		}
+		ret = append(ret, arg)
+	}
+	return ret
+}
+
+// isBoolFlag - не требует ли флаг значения.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// openInputs - открывает входы names ("-" - stdin). Каждый вход заканчивается переводом строки,
+// поэтому входы можно читать подряд как один поток. Функция closeInputs закрывает открытые файлы.
+func openInputs(names []string, stdin io.Reader) (inputs []io.Reader, closeInputs func(), err error) {
+	var files []*os.File
+	closeInputs = func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	readers := make([]io.Reader, 0, len(names))
+	for _, name := range names {
+		if name == "-" {
+			readers = append(readers, &terminated{r: stdin})
+			continue
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			closeInputs()
+			return nil, nil, err
+		}
+		files = append(files, f)
+		readers = append(readers, &terminated{r: f})
+	}
+	return readers, closeInputs, nil
+}
+
+// terminated - вход, который заканчивается переводом строки: если непустой вход r им не
+// заканчивается, перевод строки добавляется, чтобы последняя строка не склеилась с первой
+// строкой следующего входа.
+type terminated struct {
+	r    io.Reader
+	open bool // последняя прочитанная строка не закончена
+	eof  bool
+}
+
+func (t *terminated) Read(p []byte) (int, error) {
+	if t.eof {
+		if t.open && len(p) > 0 {
+			p[0], t.open = '\n', false
+			return 1, io.EOF
+		}
+		return 0, io.EOF
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.open = p[n-1] != '\n'
+	}
+	if err == io.EOF {
+		t.eof = true
+		if n == 0 {
+			return t.Read(p)
+		}
+		err = nil
+	}
+	return n, err
+}
+
+// displayName - имя входа для сообщений.
+func displayName(name string) string {
+	if name == "-" {
+		return "stdin"
+	}
+	return name
+}
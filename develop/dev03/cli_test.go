@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+var runTests = []struct {
+	name   string
+	args   []string
+	stdin  string
+	code   int
+	stdout string
+	stderr string
+}{
+	{name: "test1", stdin: "b\nc\na\n", stdout: "a\nb\nc\n"},
+	{name: "test2", args: []string{"-"}, stdin: "b\nc\na", stdout: "a\nb\nc\n"},
+	{name: "test3", args: []string{"-k", "2nr"}, stdin: "x 10\ny 9\nz 100\n", stdout: "z 100\nx 10\ny 9\n"},
+	{name: "test4", args: []string{"-u", "-S", "1"}, stdin: "b\na\nb\n", stdout: "a\nb\n"},
+	{name: "test5", args: []string{"-c"}, stdin: "a\nb\n", code: exitOK},
+	{name: "test6", args: []string{"-c"}, stdin: "a\nc\nb\n", code: exitDisorder, stderr: "stdin:3: disorder: b\n"},
+	{name: "test7", args: []string{"-c", "-", "-"}, code: exitError, stderr: "extra operand \"-\" not allowed with -c\n"},
+	{name: "test8", args: []string{"-S", "1Q"}, code: exitError},
+	{name: "test9", args: []string{"-x"}, code: exitError},
+	{name: "test10", args: []string{"-m"}, stdin: "b\na\n", stdout: "b\na\n"},
+	{name: "test11", args: []string{"-m", "-check-order"}, stdin: "b\na\n", code: exitError, stderr: "stdin:2: input is not sorted: a\n"},
+	{name: "test12", args: []string{"-c", "-m"}, code: exitError, stderr: "options -c and -m are incompatible\n"},
+	{name: "test13", args: []string{"-V"}, stdin: "file10\nfile2\nfile1\n", stdout: "file1\nfile2\nfile10\n"},
+	{name: "test14", args: []string{"-collate", "ru"}, stdin: "zebra\nёж\nеж\n", stdout: "еж\nёж\nzebra\n"},
+	{name: "test15", args: []string{"-collate", "xx"}, code: exitError, stderr: "unknown collation \"xx\", expected one of en, ru\n"},
+	{name: "test16", args: []string{"-count", "-k", "2"}, stdin: "a x\nb y\nc x\n", stdout: "      2 a x\n      1 b y\n"},
+	{name: "test17", args: []string{"-u", "-k", "1,1n"}, stdin: "18446744073709551616\n18446744073709551617\n", stdout: "18446744073709551616\n18446744073709551617\n"},
+	{name: "test18", args: []string{"-k2,2n"}, stdin: "x 10\ny 9\n", stdout: "y 9\nx 10\n"},
+	{name: "test19", args: []string{"-t:", "-k2nr", "-k1,1"}, stdin: "a:1\nb:3\nc:1\n", stdout: "b:3\na:1\nc:1\n"},
+	{name: "test20", args: []string{"-t", "-k2"}, stdin: "a-k2\nb-k1\n", stdout: "a-k2\nb-k1\n"},
+	{name: "test21", args: []string{"-k0"}, code: exitError},
+	{name: "test22", args: []string{"-check-order"}, stdin: "b\na\n", code: exitError, stderr: "option -check-order requires -m\n"},
+}
+
+func TestRun(t *testing.T) {
+	for _, test := range runTests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+			require.Equal(t, test.code, code, stderr.String())
+			require.Equal(t, test.stdout, stdout.String())
+			if test.stderr != "" {
+				require.Equal(t, test.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	dir := t.TempDir()
+	in1 := filepath.Join(dir, "in1.txt")
+	in2 := filepath.Join(dir, "in2.txt")
+	require.NoError(t, os.WriteFile(in1, []byte("d\nb"), 0o640))
+	require.NoError(t, os.WriteFile(in2, []byte("c\na\n"), 0o600))
+
+	// Последняя строка in1 без перевода строки не склеивается с первой строкой stdin и in2.
+	var stdout, stderr bytes.Buffer
+	code := run([]string{in1, "-", in2}, strings.NewReader("e"), &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Equal(t, "a\nb\nc\nd\ne\n", stdout.String())
+
+	sorted := filepath.Join(dir, "sorted.txt")
+	require.NoError(t, os.WriteFile(sorted, []byte("a\nc\n"), 0o600))
+	stdout.Reset()
+	code = run([]string{"-m", sorted, "-"}, strings.NewReader("b\nd\n"), &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Equal(t, "a\nb\nc\nd\n", stdout.String())
+
+	// Сортировка на месте: -o совпадает со входом, права доступа сохраняются.
+	stdout.Reset()
+	code = run([]string{"-o", in1, in1, in2}, nil, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	require.Empty(t, stdout.String())
+	b, err := os.ReadFile(in1)
+	require.NoError(t, err)
+	require.Equal(t, "a\nb\nc\nd\n", string(b))
+	fi, err := os.Stat(in1)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o640), fi.Mode().Perm())
+
+	out := filepath.Join(dir, "sub", "out.txt")
+	require.NoError(t, os.Mkdir(filepath.Dir(out), 0o700))
+	code = run([]string{"-S", "1", "-o", out, in2}, nil, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	b, err = os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "a\nc\n", string(b))
+
+	// При ошибке входа файл результата не изменяется, временные файлы удаляются.
+	code = run([]string{"-o", out, filepath.Join(dir, "missing.txt")}, nil, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "missing.txt")
+	b, err = os.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "a\nc\n", string(b))
+	entries, err := os.ReadDir(filepath.Dir(out))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestRunLongLine(t *testing.T) {
+	// Строка длиннее буфера bufio.Scanner (64 КиБ) не теряется при сортировке на месте.
+	f := filepath.Join(t.TempDir(), "long.txt")
+	long := strings.Repeat("x", 70000)
+	require.NoError(t, os.WriteFile(f, []byte("b\n"+long+"\na\n"), 0o600))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-o", f, f}, nil, &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	b, err := os.ReadFile(f)
+	require.NoError(t, err)
+	require.Equal(t, "a\nb\n"+long+"\n", string(b))
+
+	code = run(nil, iotest.ErrReader(io.ErrUnexpectedEOF), &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), io.ErrUnexpectedEOF.Error())
+}
+
+var terminatedTests = []struct {
+	name  string
+	input string
+	exp   string
+}{
+	{name: "test1", input: "", exp: ""},
+	{name: "test2", input: "a", exp: "a\n"},
+	{name: "test3", input: "a\n", exp: "a\n"},
+	{name: "test4", input: "a\nbc", exp: "a\nbc\n"},
+}
+
+func TestTerminated(t *testing.T) {
+	for _, test := range terminatedTests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := io.ReadAll(&terminated{r: strings.NewReader(test.input)})
+			require.NoError(t, err)
+			require.Equal(t, test.exp, string(b))
+
+			b, err = io.ReadAll(&terminated{r: iotest.OneByteReader(strings.NewReader(test.input))})
+			require.NoError(t, err)
+			require.Equal(t, test.exp, string(b))
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// collator - сопоставление строк по правилам Unicode Collation Algorithm с настройками
+// языка из CLDR: строки сравниваются по базовым буквам, затем по диакритике, затем по регистру.
+// collate.Collator нельзя использовать из нескольких горутин, поэтому для параллельной
+// сортировки экземпляры берутся из пула.
+type collator struct {
+	pool  sync.Pool
+	first *unicode.RangeTable
+}
+
+// collation - профиль сопоставления: язык CLDR и письменность, которая ставится раньше
+// остальных букв (nil - корневой порядок CLDR, латиница раньше кириллицы).
+type collation struct {
+	tag   language.Tag
+	first *unicode.RangeTable
+}
+
+// collations - профили сопоставления. В профиле ru кириллица идёт раньше латиницы, как
+// [reorder Cyrl] в CLDR.
+var collations = map[string]collation{
+	"en": {tag: language.English},
+	"ru": {tag: language.Russian, first: unicode.Cyrillic},
+}
+
+// newCollator - профиль сопоставления для языка lang.
+func newCollator(lang string) (*collator, error) {
+	prof, ok := collations[lang]
+	if !ok {
+		names := make([]string, 0, len(collations))
+		for name := range collations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown collation %q, expected one of %s", lang, strings.Join(names, ", "))
+	}
+	c := &collator{first: prof.first}
+	c.pool.New = func() any { return collate.New(prof.tag) }
+	return c, nil
+}
+
+// key - ключ сопоставления строки s: строки сравниваются по правилам collator при побайтовом
+// сравнении их ключей.
+func (c *collator) key(s string) string {
+	col := c.pool.Get().(*collate.Collator)
+	defer c.pool.Put(col)
+	var buf collate.Buffer
+	k := col.KeyFromString(&buf, s)
+	if c.first == nil {
+		return string(k)
+	}
+	return string(append([]byte{c.class(s)}, k...))
+}
+
+// class - класс письменности первого символа s: x/text не умеет переставлять письменности,
+// поэтому для профиля с first ключ начинается с этого класса. Цифры и знаки остаются раньше
+// букв, буквы first - раньше остальных. Порядок письменностей учитывается только по первому
+// символу строки.
+func (c *collator) class(s string) byte {
+	r, _ := utf8.DecodeRuneInString(s)
+	switch {
+	case !unicode.IsLetter(r):
+		return 0
+	case unicode.Is(c.first, r):
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var collateTests = []struct {
+	name     string
+	lang     string
+	a, b     string
+	expected int
+}{
+	{name: "test1", lang: "ru", a: "яблоко", b: "apple", expected: -1},
+	{name: "test2", lang: "en", a: "яблоко", b: "apple", expected: 1},
+	{name: "test3", lang: "en", a: "Banana", b: "apple", expected: 1},
+	{name: "test4", lang: "en", a: "a", b: "A", expected: -1},
+	{name: "test5", lang: "en", a: "Ab", b: "ab", expected: 1},
+	{name: "test6", lang: "en", a: "éclair", b: "eclair", expected: 1},
+	{name: "test7", lang: "en", a: "éclair", b: "eclairs", expected: -1},
+	{name: "test8", lang: "en", a: "e\u0301clair", b: "éclair", expected: 0},
+	{name: "test9", lang: "ru", a: "ёж", b: "еж", expected: 1},
+	{name: "test10", lang: "ru", a: "ёж", b: "ель", expected: -1},
+	{name: "test11", lang: "ru", a: "йод", b: "иск", expected: 1},
+	{name: "test12", lang: "en", a: "a b", b: "a-b", expected: -1},
+	{name: "test13", lang: "en", a: "a-b", b: "ab", expected: -1},
+	{name: "test14", lang: "en", a: "9", b: "a", expected: -1},
+	{name: "test15", lang: "en", a: "Straße", b: "strasse", expected: 1},
+	{name: "test16", lang: "en", a: "łódź", b: "lody", expected: 1},
+	{name: "test17", lang: "en", a: "łódź", b: "m", expected: -1},
+	{name: "test18", lang: "en", a: "øre", b: "p", expected: -1},
+	{name: "test19", lang: "en", a: "ðe", b: "f", expected: -1},
+	{name: "test20", lang: "en", a: "ßa", b: "st", expected: -1},
+	{name: "test21", lang: "ru", a: "9", b: "яблоко", expected: -1},
+	{name: "test22", lang: "ru", a: "Яблоко", b: "яблоко", expected: 1},
+}
+
+func TestCollate(t *testing.T) {
+	for _, test := range collateTests {
+		t.Run(test.name, func(t *testing.T) {
+			col, err := newCollator(test.lang)
+			require.NoError(t, err)
+			a, b := col.key(test.a), col.key(test.b)
+			require.Equal(t, test.expected, strings.Compare(a, b))
+		})
+	}
+}
+
+func TestCollateSort(t *testing.T) {
+	words := []string{"Яблоко", "apple", "ёж", "Ель", "еж", "Banana", "банан", "zebra", "éclair", "Eclair"}
+	for lang, expected := range map[string][]string{
+		"ru": {"банан", "еж", "ёж", "Ель", "Яблоко", "apple", "Banana", "Eclair", "éclair", "zebra"},
+		"en": {"apple", "Banana", "Eclair", "éclair", "zebra", "банан", "еж", "ёж", "Ель", "Яблоко"},
+	} {
+		col, err := newCollator(lang)
+		require.NoError(t, err)
+		fl := SortingFlags{collate: col}
+		actual := append([]string(nil), words...)
+		sort.SliceStable(actual, func(i, j int) bool { return fl.compare(actual[i], actual[j]) < 0 })
+		require.Equal(t, expected, actual, lang)
+	}
+
+	// Параллельная сортировка использует collator из нескольких горутин.
+	col, err := newCollator("ru")
+	require.NoError(t, err)
+	lines := randomLines(5*minChunk, 5)
+	fl := SortingFlags{collate: col}
+	expected := sortLines(append([]string(nil), lines...), &fl)
+	fl.parallel = 4
+	require.Equal(t, expected, sortLines(append([]string(nil), lines...), &fl))
+
+	_, err = newCollator("de")
+	require.EqualError(t, err, `unknown collation "de", expected one of en, ru`)
+}
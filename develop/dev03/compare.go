@@ -0,0 +1,208 @@
+package main
+
+import (
+	"strings"
+)
+
+// keyValue - значение ключа, разобранное для сравнения с модификаторами.
+type keyValue struct {
+	text string  // текст ключа в нижнем регистре или ключ сопоставления для строкового сравнения
+	num  decimal // число для n и h
+	rank int     // ранг суффикса для h или номер месяца для M
+}
+
+// parseKeyValue - разбирает текст ключа s для сравнения с модификаторами opts. Текст без
+// модификаторов сравнивается по правилам col, а если col равен nil - побайтово без учёта регистра.
+func parseKeyValue(s string, opts keyOpts, col *collator) keyValue {
+	if opts.blanks {
+		s = trimBlanks(s)
+	}
+	switch {
+	case opts.num:
+		return keyValue{num: parseDecimal(s)}
+	case opts.human:
+		v, rank := parseHuman(s)
+		return keyValue{num: v, rank: rank}
+	case opts.month:
+		return keyValue{rank: monthIndex(s)}
+	case opts.version:
+		return keyValue{text: strings.ToLower(s)}
+	case col != nil:
+		return keyValue{text: col.key(s)}
+	}
+	return keyValue{text: strings.ToLower(s)}
+}
+
+// compareValues - сравнивает разобранные значения ключей с модификаторами opts без учёта r.
+// Числа с суффиксами (h) сравниваются как в sort -h: сначала по знаку, затем по суффиксу,
+// затем по значению. Возвращает -1, 0 или 1.
+func compareValues(a, b keyValue, opts keyOpts) int {
+	switch {
+	case opts.num:
+		return compareDecimal(a.num, b.num)
+	case opts.human:
+		if c := compareInt(a.num.sign(), b.num.sign()); c != 0 {
+			return c
+		}
+		c := compareInt(a.rank, b.rank)
+		if a.num.neg {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+		return compareDecimal(a.num, b.num)
+	case opts.month:
+		return compareInt(a.rank, b.rank)
+	case opts.version:
+		return compareVersion(a.text, b.text)
+	}
+	return strings.Compare(a.text, b.text)
+}
+
+// compareKey - сравнивает тексты ключей a и b с модификаторами opts без учёта r.
+func compareKey(a, b string, opts keyOpts) int {
+	return compareValues(parseKeyValue(a, opts, nil), parseKeyValue(b, opts, nil), opts)
+}
+
+// trimBlanks - убирает пробелы и табуляции в начале строки.
+func trimBlanks(s string) string {
+	return strings.TrimLeft(s, " \t")
+}
+
+// numberPrefix - длина числа в начале s (после пробелов): необязательный минус, цифры и дробная часть.
+func numberPrefix(s string) int {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for ; i < len(s) && s[i] >= '0' && s[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	return i
+}
+
+// decimal - десятичное число ключа n или h. Число хранится цифрами, а не float64, чтобы
+// сравнение было точным при любой длине числа.
+type decimal struct {
+	neg  bool
+	int  string // целая часть без ведущих нулей
+	frac string // дробная часть без хвостовых нулей
+}
+
+// parseDecimal - число в начале строки, как в sort -n. Строка без числа считается нулём.
+func parseDecimal(s string) decimal {
+	s = trimBlanks(s)
+	s = s[:numberPrefix(s)]
+	var d decimal
+	if strings.HasPrefix(s, "-") {
+		d.neg, s = true, s[1:]
+	}
+	i, f, _ := strings.Cut(s, ".")
+	d.int = strings.TrimLeft(i, "0")
+	d.frac = strings.TrimRight(f, "0")
+	if d.sign() == 0 {
+		d.neg = false
+	}
+	return d
+}
+
+// sign - знак числа.
+func (d decimal) sign() int {
+	switch {
+	case d.int == "" && d.frac == "":
+		return 0
+	case d.neg:
+		return -1
+	}
+	return 1
+}
+
+// compareDecimal - точное сравнение чисел: по знаку, затем по длине целой части, по цифрам
+// целой части и по цифрам дробной части.
+func compareDecimal(a, b decimal) int {
+	if c := compareInt(a.sign(), b.sign()); c != 0 {
+		return c
+	}
+	c := compareInt(len(a.int), len(b.int))
+	if c == 0 {
+		c = strings.Compare(a.int, b.int)
+	}
+	if c == 0 {
+		c = strings.Compare(a.frac, b.frac)
+	}
+	if a.neg {
+		return -c
+	}
+	return c
+}
+
+// humanSuffixes - суффиксы -h в порядке возрастания.
+const humanSuffixes = "KMGTPEZY"
+
+// parseHuman - число в начале строки и ранг его суффикса (0 - без суффикса).
+func parseHuman(s string) (decimal, int) {
+	s = trimBlanks(s)
+	n := numberPrefix(s)
+	if n == 0 {
+		return decimal{}, 0
+	}
+	v := parseDecimal(s)
+	if n == len(s) {
+		return v, 0
+	}
+	if s[n] == 'k' {
+		return v, 1
+	}
+	return v, strings.IndexByte(humanSuffixes, s[n]) + 1
+}
+
+// monthTables - таблицы начал названий месяцев по языкам: для каждого месяца - варианты
+// сокращений, с которых начинаются его названия во всех падежах.
+var monthTables = map[string][12][]string{
+	"en": {
+		{"jan"}, {"feb"}, {"mar"}, {"apr"}, {"may"}, {"jun"},
+		{"jul"}, {"aug"}, {"sep"}, {"oct"}, {"nov"}, {"dec"},
+	},
+	"ru": {
+		{"янв"}, {"фев"}, {"мар"}, {"апр"}, {"май", "мая"}, {"июн"},
+		{"июл"}, {"авг"}, {"сен"}, {"окт"}, {"ноя"}, {"дек"},
+	},
+}
+
+// monthIndex - номер месяца (1-12) по началу строки на любом из языков monthTables, 0 - не месяц.
+// Регистр не учитывается: JAN, Янв и января распознаются одинаково.
+func monthIndex(s string) int {
+	s = strings.ToLower(trimBlanks(s))
+	for _, table := range monthTables {
+		for i, prefixes := range table {
+			for _, p := range prefixes {
+				if strings.HasPrefix(s, p) {
+					return i + 1
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// compareInt - сравнение целых чисел.
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var compareKeyTests = []struct {
+	name     string
+	a, b     string
+	opts     keyOpts
+	expected int
+}{
+	{name: "test1", a: "10", b: "9", opts: keyOpts{num: true}, expected: 1},
+	{name: "test2", a: "-3.5x", b: "-3.25", opts: keyOpts{num: true}, expected: -1},
+	{name: "test3", a: "abc", b: "0", opts: keyOpts{num: true}, expected: 0},
+	{name: "test4", a: "  7", b: "10", opts: keyOpts{num: true}, expected: -1},
+	{name: "test5", a: "1K", b: "1000", opts: keyOpts{human: true}, expected: 1},
+	{name: "test6", a: "2k", b: "1M", opts: keyOpts{human: true}, expected: -1},
+	{name: "test7", a: "-1G", b: "-1K", opts: keyOpts{human: true}, expected: -1},
+	{name: "test8", a: "1.5G", b: "1.2G", opts: keyOpts{human: true}, expected: 1},
+	{name: "test9", a: "DEC", b: "jan", opts: keyOpts{month: true}, expected: 1},
+	{name: "test10", a: "foo", b: "jan", opts: keyOpts{month: true}, expected: -1},
+	{name: "test11", a: "  b", b: "a", opts: keyOpts{blanks: true}, expected: 1},
+	{name: "test12", a: "  b", b: "a", expected: -1},
+	{name: "test13", a: "B", b: "a", expected: 1},
+	{name: "test14", a: "ноя", b: "дек", opts: keyOpts{month: true}, expected: -1},
+	{name: "test15", a: "Января", b: "фев", opts: keyOpts{month: true}, expected: -1},
+	{name: "test16", a: "мая", b: "март", opts: keyOpts{month: true}, expected: 1},
+	{name: "test17", a: "May", b: "май", opts: keyOpts{month: true}, expected: 0},
+	{name: "test18", a: "  2T", b: "900G", opts: keyOpts{human: true, blanks: true}, expected: 1},
+	{name: "test19", a: "File10", b: "file9", opts: keyOpts{version: true}, expected: 1},
+	{name: "test20", a: "  v2", b: "v10", opts: keyOpts{version: true, blanks: true}, expected: -1},
+	{name: "test21", a: "18446744073709551616", b: "18446744073709551617", opts: keyOpts{num: true}, expected: -1},
+	{name: "test22", a: "007.50", b: "7.5", opts: keyOpts{num: true}, expected: 0},
+	{name: "test23", a: "-0", b: "0.000", opts: keyOpts{num: true}, expected: 0},
+	{name: "test24", a: "-12.5", b: "-12.49", opts: keyOpts{num: true}, expected: -1},
+	{name: "test25", a: "0.1", b: "0.09999999999999999999", opts: keyOpts{num: true}, expected: 1},
+	{name: "test26", a: "9007199254740993K", b: "9007199254740992K", opts: keyOpts{human: true}, expected: 1},
+}
+
+func TestCompareKey(t *testing.T) {
+	for _, test := range compareKeyTests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, compareKey(test.a, test.b, test.opts))
+		})
+	}
+}
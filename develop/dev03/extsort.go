@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// parsedOverhead - расход памяти на разобранную строку сверх длины её текстов: сама структура,
+// указатель на неё для сортировки и буфер слияния порций при параллельной сортировке.
+const parsedOverhead = int64(unsafe.Sizeof(parsedLine{})) + 2*int64(unsafe.Sizeof(&parsedLine{}))
+
+// keyOverhead - расход памяти на значение одного ключа сверх длины его текстов.
+const keyOverhead = int64(unsafe.Sizeof(keyValue{}))
+
+// size - примерный объём памяти, который занимает разобранная строка при сортировке порции.
+func (pl *parsedLine) size() int64 {
+	n := parsedOverhead + int64(len(pl.line)+len(pl.whole))
+	for _, k := range pl.keys {
+		n += keyOverhead + int64(len(k.text)+len(k.num.int)+len(k.num.frac))
+	}
+	return n
+}
+
+// parseSize - разбирает размер буфера -S как в GNU sort: число с суффиксом b (байты),
+// K, M, G, T (степени 1024). Число без суффикса задаётся в килобайтах.
+func parseSize(s string) (int64, error) {
+	num, mult := s, int64(1<<10)
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte("bKMGT", s[n-1]); i >= 0 {
+			num, mult = s[:n-1], 1
+			if i > 0 {
+				mult = 1 << (10 * i)
+			}
+		}
+	}
+	v, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || v <= 0 || v > (1<<62)/mult {
+		return 0, fmt.Errorf("invalid buffer size %q", s)
+	}
+	return v * mult, nil
+}
+
+// lineReader - построчное чтение без ограничения длины строки. Перевод строки в конце отбрасывается,
+// для входных файлов - вместе с \r, как у bufio.Scanner.
+type lineReader struct {
+	r      *bufio.Reader
+	trimCR bool
+}
+
+// newLineReader - создаёт lineReader для входного файла r.
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r), trimCR: true}
+}
+
+// next - следующая строка или io.EOF в конце входа.
+func (lr *lineReader) next() (string, error) {
+	line, err := lr.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	if lr.trimCR {
+		line = strings.TrimSuffix(line, "\r")
+	}
+	return line, nil
+}
+
+// lineWriter - запись строк, каждая строка заканчивается переводом строки, как у sort.
+// При fl.unique из подряд идущих строк, равных по ключам сортировки, записывается первая,
+// а при fl.count - первая с числом строк в группе в формате uniq -c.
+type lineWriter struct {
+	w     *bufio.Writer
+	fl    *SortingFlags
+	last  parsedLine // первая строка текущей группы
+	count int        // число строк в текущей группе, 0 - группы нет
+}
+
+// newLineWriter - создаёт lineWriter для w.
+func newLineWriter(w io.Writer, fl *SortingFlags) *lineWriter {
+	return &lineWriter{w: bufio.NewWriter(w), fl: fl}
+}
+
+// write - записывает очередную строку.
+func (lw *lineWriter) write(line string) error {
+	if !lw.fl.unique {
+		return lw.writeLine(line)
+	}
+	pl := lw.fl.parse(line)
+	if lw.count > 0 && lw.fl.compareKeys(&lw.last, &pl) == 0 {
+		lw.count++
+		return nil
+	}
+	if err := lw.endGroup(); err != nil {
+		return err
+	}
+	lw.last, lw.count = pl, 1
+	if lw.fl.count {
+		return nil
+	}
+	return lw.writeLine(line)
+}
+
+// endGroup - при fl.count записывает текущую группу с числом её строк.
+func (lw *lineWriter) endGroup() error {
+	if !lw.fl.count || lw.count == 0 {
+		return nil
+	}
+	line := fmt.Sprintf("%7d %s", lw.count, lw.last.line)
+	lw.count = 0
+	return lw.writeLine(line)
+}
+
+// writeLine - записывает строку без проверки повторов.
+func (lw *lineWriter) writeLine(line string) error {
+	lw.w.WriteString(line)
+	return lw.w.WriteByte('\n')
+}
+
+// flush - дописывает последнюю группу и буферизованные данные.
+func (lw *lineWriter) flush() error {
+	if err := lw.endGroup(); err != nil {
+		return err
+	}
+	return lw.w.Flush()
+}
+
+// mergeBatch - наибольшее число временных файлов, сливаемых за один проход.
+const mergeBatch = 16
+
+// externalSort - внешняя сортировка: читает строки из r порциями, которые вместе с разобранными
+// ключами занимают не больше fl.bufSize байт (см. parsedLine.size), сортирует каждую порцию и записывает её во временный файл в каталоге fl.tempDir,
+// затем сливает отсортированные порции в w (по mergeBatch файлов за проход). Если вход
+// помещается в одну порцию, временные файлы не создаются. При fl.unique строки, равные по ключам,
+// убираются внутри порций (кроме подсчёта повторов при fl.count) и среди соседних строк результата.
+func externalSort(r io.Reader, w io.Writer, fl *SortingFlags) error {
+	var runs []string
+	defer func() {
+		for _, name := range runs {
+			os.Remove(name)
+		}
+	}()
+
+	lr := newLineReader(r)
+	var chunk []parsedLine
+	var size int64
+	for {
+		line, rerr := lr.next()
+		if rerr != nil && rerr != io.EOF {
+			return rerr
+		}
+		if rerr == nil {
+			// Строки разбираются при чтении: размер порции учитывает разобранные ключи.
+			pl := fl.parse(line)
+			chunk = append(chunk, pl)
+			size += pl.size()
+		}
+		if size < fl.bufSize && rerr == nil {
+			continue
+		}
+		sorted := make([]string, len(chunk))
+		for i, pl := range sortParsed(chunk, fl) {
+			sorted[i] = pl.line
+		}
+		if rerr == io.EOF && len(runs) == 0 {
+			// Всё поместилось в память: сливать нечего.
+			return writeLines(w, sorted, fl)
+		}
+		if len(chunk) > 0 {
+			if fl.unique && !fl.count {
+				// Повторы внутри порции не нужны для результата; при count они нужны для подсчёта.
+				sorted = uniqueLines(sorted, fl)
+			}
+			name, err := writeRun(fl.tempDir, func(emit func(string) error) error {
+				for _, line := range sorted {
+					if err := emit(line); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			runs = append(runs, name)
+			chunk, size = chunk[:0], 0
+		}
+		if rerr == io.EOF {
+			break
+		}
+	}
+
+	// Промежуточные проходы сливают соседние группы файлов, сохраняя их порядок:
+	// при равных строках раньше идёт строка из более ранней порции.
+	for len(runs) > mergeBatch {
+		var merged []string
+		for i := 0; i < len(runs); i += mergeBatch {
+			batch := runs[i:min(i+mergeBatch, len(runs))]
+			name, err := writeRun(fl.tempDir, func(emit func(string) error) error {
+				return mergeFiles(batch, fl, emit)
+			})
+			if err != nil {
+				runs = append(runs, merged...)
+				return err
+			}
+			merged = append(merged, name)
+			for _, old := range batch {
+				os.Remove(old)
+			}
+		}
+		runs = merged
+	}
+
+	lw := newLineWriter(w, fl)
+	if err := mergeFiles(runs, fl, lw.write); err != nil {
+		return err
+	}
+	return lw.flush()
+}
+
+// min - меньшее из двух чисел.
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// writeLines - записывает отсортированные строки в w.
+func writeLines(w io.Writer, lines []string, fl *SortingFlags) error {
+	lw := newLineWriter(w, fl)
+	for _, line := range lines {
+		if err := lw.write(line); err != nil {
+			return err
+		}
+	}
+	return lw.flush()
+}
+
+// writeRun - создаёт временный файл в каталоге dir и записывает в него строки, которые
+// fill передаёт в emit. Возвращает имя файла; при ошибке файл удаляется.
+func writeRun(dir string, fill func(emit func(string) error) error) (string, error) {
+	f, err := os.CreateTemp(dir, "dev03-run-*")
+	if err != nil {
+		return "", err
+	}
+	bw := bufio.NewWriter(f)
+	err = fill(func(line string) error {
+		bw.WriteString(line)
+		return bw.WriteByte('\n')
+	})
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// mergeFiles - сливает отсортированные временные файлы names, передавая строки в emit.
+func mergeFiles(names []string, fl *SortingFlags, emit func(string) error) error {
+	readers := make([]lineSource, 0, len(names))
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		readers = append(readers, &lineReader{r: bufio.NewReader(f)})
+	}
+	return mergeLines(readers, fl, emit)
+}
+
+// lineSource - вход слияния: next возвращает следующую строку или io.EOF в конце входа.
+type lineSource interface {
+	next() (string, error)
+}
+
+// mergeItem - текущая строка одного из сливаемых входов.
+type mergeItem struct {
+	line parsedLine
+	src  int // номер входа: при равных строках первым идёт вход с меньшим номером
+}
+
+// mergeHeap - куча текущих строк входов, упорядоченная компаратором флагов.
+type mergeHeap struct {
+	items []mergeItem
+	fl    *SortingFlags
+}
+
+func (h *mergeHeap) Len() int      { return len(h.items) }
+func (h *mergeHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *mergeHeap) Push(x any)    { h.items = append(h.items, x.(mergeItem)) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	if c := h.fl.compareParsed(&h.items[i].line, &h.items[j].line); c != 0 {
+		return c < 0
+	}
+	return h.items[i].src < h.items[j].src
+}
+
+func (h *mergeHeap) Pop() any {
+	n := len(h.items) - 1
+	item := h.items[n]
+	h.items = h.items[:n]
+	return item
+}
+
+// mergeLines - k-путевое слияние отсортированных входов: строки передаются в emit по порядку.
+func mergeLines(inputs []lineSource, fl *SortingFlags, emit func(string) error) error {
+	h := &mergeHeap{fl: fl}
+	for i, lr := range inputs {
+		line, err := lr.next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		h.items = append(h.items, mergeItem{line: fl.parse(line), src: i})
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		item := h.items[0]
+		if err := emit(item.line.line); err != nil {
+			return err
+		}
+		line, err := inputs[item.src].next()
+		switch {
+		case err == io.EOF:
+			heap.Pop(h)
+		case err != nil:
+			return err
+		default:
+			h.items[0].line = fl.parse(line)
+			heap.Fix(h, 0)
+		}
+	}
+	return nil
+}
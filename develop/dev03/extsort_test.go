@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var parseSizeTests = []struct {
+	name, size string
+	expected   int64
+	err        bool
+}{
+	{name: "test1", size: "10", expected: 10 << 10},
+	{name: "test2", size: "100b", expected: 100},
+	{name: "test3", size: "64K", expected: 64 << 10},
+	{name: "test4", size: "2M", expected: 2 << 20},
+	{name: "test5", size: "1G", expected: 1 << 30},
+	{name: "test6", size: "1T", expected: 1 << 40},
+	{name: "test7", size: "", err: true},
+	{name: "test8", size: "0", err: true},
+	{name: "test9", size: "-5K", err: true},
+	{name: "test10", size: "1X", err: true},
+	{name: "test11", size: "9999999T", err: true},
+}
+
+func TestParseSize(t *testing.T) {
+	for _, test := range parseSizeTests {
+		t.Run(test.name, func(t *testing.T) {
+			size, err := parseSize(test.size)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, size)
+		})
+	}
+}
+
+// randomLines - n случайных строк из нескольких колонок с повторами.
+func randomLines(n int, seed int64) []string {
+	rnd := rand.New(rand.NewSource(seed))
+	words := []string{"alpha", "Beta", "gamma", "дельта", "Эпсилон", "zeta"}
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%s %d %s", words[rnd.Intn(len(words))], rnd.Intn(100), words[rnd.Intn(len(words))])
+	}
+	return lines
+}
+
+var externalSortTests = []struct {
+	name string
+	keys []string
+	fl   SortingFlags
+}{
+	{name: "test1"},
+	{name: "test2", keys: []string{"2,2n", "1,1r"}},
+	{name: "test3", keys: []string{"3,3"}, fl: SortingFlags{reverse: true}},
+	{name: "test4", fl: SortingFlags{unique: true}},
+	{name: "test5", keys: []string{"2,2"}, fl: SortingFlags{num: true}},
+	{name: "test6", keys: []string{"2,2n"}, fl: SortingFlags{unique: true}},
+	{name: "test7", keys: []string{"1,1"}, fl: SortingFlags{unique: true, count: true}},
+}
+
+func TestExternalSort(t *testing.T) {
+	lines := randomLines(2000, 1)
+	input := strings.Join(lines, "\n") + "\n"
+	for _, test := range externalSortTests {
+		t.Run(test.name, func(t *testing.T) {
+			fl := test.fl
+			for _, spec := range test.keys {
+				require.NoError(t, (*keyList)(&fl.keys).Set(spec))
+			}
+			expected := sortFile(append([]string(nil), lines...), &fl)
+
+			for _, size := range []int64{1 << 8, 1 << 12, 1 << 20} {
+				dir := t.TempDir()
+				fl.bufSize, fl.tempDir = size, dir
+				var out bytes.Buffer
+				require.NoError(t, externalSort(strings.NewReader(input), &out, &fl))
+				require.Equal(t, string(expected), out.String(), "buffer size %d", size)
+
+				// Временные файлы удаляются после слияния.
+				entries, err := os.ReadDir(dir)
+				require.NoError(t, err)
+				require.Empty(t, entries)
+			}
+		})
+	}
+}
+
+func TestExternalSortLines(t *testing.T) {
+	fl := &SortingFlags{bufSize: 1, tempDir: t.TempDir()}
+	var out bytes.Buffer
+	// Последняя строка без перевода строки, \r перед переводом строки отбрасывается, длинная строка не обрезается.
+	long := strings.Repeat("z", 1<<17)
+	require.NoError(t, externalSort(strings.NewReader("b\r\n"+long+"\n\na"), &out, fl))
+	require.Equal(t, "\na\nb\n"+long+"\n", out.String())
+
+	out.Reset()
+	require.NoError(t, externalSort(strings.NewReader(""), &out, fl))
+	require.Empty(t, out.String())
+
+	fl.tempDir = "/nonexistent/dir"
+	require.Error(t, externalSort(strings.NewReader("b\na\n"), &out, fl))
+}
+
+func TestParsedLineSize(t *testing.T) {
+	line := "Alpha 12 beta"
+	fl := SortingFlags{}
+	whole := fl.parse(line)
+	// Строка, её копия в нижнем регистре и ключ по всей строке.
+	require.GreaterOrEqual(t, whole.size(), parsedOverhead+keyOverhead+3*int64(len(line)))
+
+	for _, spec := range []string{"1,1", "2,2n", "3,3"} {
+		require.NoError(t, (*keyList)(&fl.keys).Set(spec))
+	}
+	keyed := fl.parse(line)
+	require.Equal(t, parsedOverhead+3*keyOverhead+int64(2*len(line)+len("alpha")+len("12")+len(" beta")), keyed.size())
+}
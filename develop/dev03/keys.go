@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// keyOpts - модификаторы сравнения ключа: n, r, M, h, V, b.
+type keyOpts struct {
+	num     bool // по числовому значению
+	reverse bool // в обратном порядке
+	month   bool // по названию месяца
+	human   bool // по числовому значению с суффиксами K, M, G...
+	version bool // естественное сравнение: числа внутри текста сравниваются как числа
+	blanks  bool // пропускать пробелы в начале поля
+}
+
+// any - задан ли хотя бы один модификатор.
+func (o keyOpts) any() bool {
+	return o != keyOpts{}
+}
+
+// sortKey - ключ сортировки -k POS1[,POS2][OPTS]. Поля и символы нумеруются с 1.
+type sortKey struct {
+	startField, startChar int // startChar 0 - начало поля
+	endField, endChar     int // endField 0 - до конца строки, endChar 0 - до конца поля
+	keyOpts
+}
+
+// parseKey - разбирает описание ключа в формате GNU sort: F[.C][OPTS][,F[.C][OPTS]].
+func parseKey(spec string) (sortKey, error) {
+	var k sortKey
+	start, end, hasEnd := strings.Cut(spec, ",")
+	var err error
+	if k.startField, k.startChar, err = parseKeyPos(start, &k.keyOpts); err != nil || k.startField == 0 {
+		return k, fmt.Errorf("invalid key %q", spec)
+	}
+	if strings.Contains(start, ".") && k.startChar == 0 {
+		return k, fmt.Errorf("invalid key %q: character position 0", spec)
+	}
+	if hasEnd {
+		if k.endField, k.endChar, err = parseKeyPos(end, &k.keyOpts); err != nil || k.endField == 0 {
+			return k, fmt.Errorf("invalid key %q", spec)
+		}
+	}
+	return k, nil
+}
+
+// parseKeyPos - разбирает позицию F[.C] и следующие за ней модификаторы.
+func parseKeyPos(pos string, opts *keyOpts) (field, char int, err error) {
+	i := strings.IndexFunc(pos, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(pos)
+	}
+	num, mods := pos[:i], pos[i:]
+	f, c, hasChar := strings.Cut(num, ".")
+	if field, err = strconv.Atoi(f); err != nil {
+		return 0, 0, err
+	}
+	if hasChar {
+		if char, err = strconv.Atoi(c); err != nil {
+			return 0, 0, err
+		}
+	}
+	for _, m := range mods {
+		switch m {
+		case 'n':
+			opts.num = true
+		case 'r':
+			opts.reverse = true
+		case 'M':
+			opts.month = true
+		case 'h':
+			opts.human = true
+		case 'V':
+			opts.version = true
+		case 'b':
+			opts.blanks = true
+		default:
+			return 0, 0, fmt.Errorf("unknown key modifier %q", m)
+		}
+	}
+	return field, char, nil
+}
+
+// keyList - значение повторяемого флага -k.
+type keyList []sortKey
+
+// String - ключи в виде строки для справки flag.
+func (l *keyList) String() string {
+	return fmt.Sprint(len(*l), " keys")
+}
+
+// Set - добавляет ключ из очередного флага -k.
+func (l *keyList) Set(spec string) error {
+	k, err := parseKey(spec)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, k)
+	return nil
+}
+
+// field - границы поля в строке: [start, end).
+type field struct {
+	start, end int
+}
+
+// splitFields - границы полей строки. При пустом sep поле - серия пробелов и табуляций
+// вместе со следующими за ней непробельными символами, как в GNU sort; иначе поля разделяются
+// каждым вхождением sep.
+func splitFields(line, sep string) []field {
+	fields := make([]field, 0, 8)
+	if sep != "" {
+		start := 0
+		for {
+			i := strings.Index(line[start:], sep)
+			if i < 0 {
+				break
+			}
+			fields = append(fields, field{start, start + i})
+			start += i + len(sep)
+		}
+		return append(fields, field{start, len(line)})
+	}
+	for i := 0; i < len(line); {
+		j := i
+		for j < len(line) && isBlank(line[j]) {
+			j++
+		}
+		for j < len(line) && !isBlank(line[j]) {
+			j++
+		}
+		fields = append(fields, field{i, j})
+		i = j
+	}
+	if len(fields) == 0 {
+		fields = append(fields, field{0, 0})
+	}
+	return fields
+}
+
+// extract - часть строки, которую сравнивает ключ, при разделителе полей sep. Возвращается
+// подстрока исходной строки, поэтому разделители внутри ключа сохраняются как есть.
+func (k sortKey) extract(line, sep string) string {
+	fields := splitFields(line, sep)
+	if k.startField > len(fields) {
+		return ""
+	}
+	start := k.offset(line, fields[k.startField-1], k.startChar)
+	end := len(line)
+	if k.endField > 0 && k.endField <= len(fields) {
+		f := fields[k.endField-1]
+		end = f.end
+		if k.endChar > 0 {
+			end = k.offset(line, f, k.endChar+1)
+		}
+	}
+	if end < start {
+		return ""
+	}
+	return line[start:end]
+}
+
+// offset - смещение символа char (с 1, 0 - начало поля) в поле f. С модификатором b
+// символы отсчитываются после пробелов в начале поля.
+func (k sortKey) offset(line string, f field, char int) int {
+	i := f.start
+	if k.blanks {
+		for i < f.end && isBlank(line[i]) {
+			i++
+		}
+	}
+	for ; char > 1 && i < f.end; char-- {
+		_, w := utf8.DecodeRuneInString(line[i:f.end])
+		i += w
+	}
+	return i
+}
+
+// isBlank - пробел или табуляция.
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
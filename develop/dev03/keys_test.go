@@ -0,0 +1,157 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var parseKeyTests = []struct {
+	name, spec string
+	expected   sortKey
+	err        bool
+}{
+	{name: "test1", spec: "2", expected: sortKey{startField: 2}},
+	{name: "test2", spec: "2,2n", expected: sortKey{startField: 2, endField: 2, keyOpts: keyOpts{num: true}}},
+	{name: "test3", spec: "1.3b,1.5r", expected: sortKey{startField: 1, startChar: 3, endField: 1, endChar: 5, keyOpts: keyOpts{blanks: true, reverse: true}}},
+	{name: "test4", spec: "3Mh", expected: sortKey{startField: 3, keyOpts: keyOpts{month: true, human: true}}},
+	{name: "test5", spec: "0", err: true},
+	{name: "test6", spec: "1.0", err: true},
+	{name: "test7", spec: "1,0", err: true},
+	{name: "test8", spec: "1x", err: true},
+	{name: "test9", spec: "a", err: true},
+	{name: "test10", spec: "", err: true},
+	{name: "test11", spec: "2V", expected: sortKey{startField: 2, keyOpts: keyOpts{version: true}}},
+}
+
+func TestParseKey(t *testing.T) {
+	for _, test := range parseKeyTests {
+		t.Run(test.name, func(t *testing.T) {
+			k, err := parseKey(test.spec)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, k)
+		})
+	}
+}
+
+var extractTests = []struct {
+	name, spec, sep, line, expected string
+}{
+	{name: "test1", spec: "2", line: "a bb ccc", expected: " bb ccc"},
+	{name: "test2", spec: "2,2", line: "a bb ccc", expected: " bb"},
+	{name: "test3", spec: "1.2,2.2", line: "abc def", expected: "bc d"},
+	{name: "test4", spec: "4", line: "a bb ccc", expected: ""},
+	{name: "test5", spec: "1,5", line: "a bb", expected: "a bb"},
+	{name: "test6", spec: "2.2,2.3", line: "x ноябрь", expected: "но"},
+	{name: "test7", spec: "2.2", line: "x ", expected: ""},
+	{name: "test8", spec: "2,2", line: "a  \t bb   ccc", expected: "  \t bb"},
+	{name: "test9", spec: "2b,2", line: "a  \t bb   ccc", expected: "bb"},
+	{name: "test10", spec: "2.2b,2.3b", line: "a    bcd e", expected: "cd"},
+	{name: "test11", spec: "1,1", line: "  lead x", expected: "  lead"},
+	{name: "test12", spec: "2,2", sep: "\t", line: "a b\tc d\te", expected: "c d"},
+	{name: "test13", spec: "2", sep: "\t", line: "a b\tc d\te", expected: "c d\te"},
+	{name: "test14", spec: "3,3", sep: "::", line: "a::b::::c", expected: ""},
+	{name: "test15", spec: "4,4", sep: "::", line: "a::b::::c", expected: "c"},
+	{name: "test16", spec: "1,1", line: "", expected: ""},
+}
+
+func TestExtract(t *testing.T) {
+	for _, test := range extractTests {
+		t.Run(test.name, func(t *testing.T) {
+			k, err := parseKey(test.spec)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, k.extract(test.line, test.sep))
+		})
+	}
+}
+
+var multiKeyTests = []struct {
+	name    string
+	keys    []string
+	fl      SortingFlags
+	in, exp []string
+}{
+	{
+		name: "test1",
+		keys: []string{"2,2n", "1,1r"},
+		in:   []string{"b 10", "a 2", "c 2", "d 10", "e 1"},
+		exp:  []string{"e 1", "c 2", "a 2", "d 10", "b 10"},
+	},
+	{
+		name: "test2",
+		keys: []string{"1,1M", "2,2h"},
+		in:   []string{"feb 1K", "jan 2M", "feb 900", "jan 1G", "xxx 5"},
+		exp:  []string{"xxx 5", "jan 2M", "jan 1G", "feb 900", "feb 1K"},
+	},
+	{
+		name: "test3",
+		keys: []string{"2,2"},
+		fl:   SortingFlags{num: true, reverse: true},
+		in:   []string{"x 3", "y 20", "z 100"},
+		exp:  []string{"z 100", "y 20", "x 3"},
+	},
+	{
+		name: "test4",
+		keys: []string{"2,2n", "3,3"},
+		fl:   SortingFlags{reverse: true},
+		in:   []string{"a 1 b", "a 1 a", "a 0 c"},
+		exp:  []string{"a 0 c", "a 1 b", "a 1 a"},
+	},
+	{
+		name: "test5",
+		fl:   SortingFlags{num: true},
+		in:   []string{"10 a", "9 b", "-1 c", "x d"},
+		exp:  []string{"-1 c", "x d", "9 b", "10 a"},
+	},
+	{
+		name: "test6",
+		fl:   SortingFlags{month: true},
+		in:   []string{"дек", "  янв", "Nov", "xxx"},
+		exp:  []string{"xxx", "  янв", "Nov", "дек"},
+	},
+	{
+		name: "test7",
+		keys: []string{"2"},
+		fl:   SortingFlags{blanks: true},
+		in:   []string{"x   b", "y  c", "z a"},
+		exp:  []string{"z a", "x   b", "y  c"},
+	},
+	{
+		name: "test8",
+		keys: []string{"2"},
+		fl:   SortingFlags{human: true},
+		in:   []string{"a 1G", "b 10M", "c 512", "d 2K"},
+		exp:  []string{"c 512", "d 2K", "b 10M", "a 1G"},
+	},
+	{
+		name: "test9",
+		keys: []string{"2,2n"},
+		in:   []string{"a    10 x", "b 9 y", "c\t\t2 z"},
+		exp:  []string{"c\t\t2 z", "b 9 y", "a    10 x"},
+	},
+	{
+		name: "test10",
+		keys: []string{"3,3", "1,1"},
+		fl:   SortingFlags{sep: ";"},
+		in:   []string{"b;x y;2", "a;;2", "c;z;1"},
+		exp:  []string{"c;z;1", "a;;2", "b;x y;2"},
+	},
+}
+
+func TestMultipleKeys(t *testing.T) {
+	for _, test := range multiKeyTests {
+		t.Run(test.name, func(t *testing.T) {
+			fl := test.fl
+			for _, spec := range test.keys {
+				require.NoError(t, (*keyList)(&fl.keys).Set(spec))
+			}
+			res := sortFile(append([]string(nil), test.in...), &fl)
+			require.Equal(t, strings.Join(append(test.exp, ""), "\n"), string(res))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrUnsorted - ошибка слияния: строки входа не отсортированы.
+var ErrUnsorted = errors.New("input is not sorted")
+
+// orderedReader - вход слияния, проверяющий, что его строки отсортированы по флагам fl.
+type orderedReader struct {
+	*lineReader
+	fl   *SortingFlags
+	name string // имя входа для сообщения об ошибке
+	prev string
+	n    int
+}
+
+// next - следующая строка входа или ErrUnsorted, если она меньше предыдущей.
+func (or *orderedReader) next() (string, error) {
+	line, err := or.lineReader.next()
+	if err != nil {
+		return "", err
+	}
+	or.n++
+	if or.n > 1 && or.fl.compare(or.prev, line) > 0 {
+		return "", fmt.Errorf("%s:%d: %w: %s", or.name, or.n, ErrUnsorted, line)
+	}
+	or.prev = line
+	return line, nil
+}
+
+// mergeSorted - слияние уже отсортированных входов inputs в w без пересортировки (-m). Строки
+// сравниваются так же, как при сортировке с флагами fl, при равных строках первой идёт строка
+// из входа с меньшим номером. При check первая строка входа, меньшая предыдущей, - ошибка
+// ErrUnsorted; names - имена входов для сообщения.
+func mergeSorted(inputs []io.Reader, names []string, w io.Writer, fl *SortingFlags, check bool) error {
+	sources := make([]lineSource, len(inputs))
+	for i, r := range inputs {
+		if check {
+			sources[i] = &orderedReader{lineReader: newLineReader(r), fl: fl, name: displayName(names[i])}
+		} else {
+			sources[i] = newLineReader(r)
+		}
+	}
+	lw := newLineWriter(w, fl)
+	if err := mergeLines(sources, fl, lw.write); err != nil {
+		return err
+	}
+	return lw.flush()
+}
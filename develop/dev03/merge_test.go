@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var mergeSortedTests = []struct {
+	name   string
+	inputs []string
+	keys   []string
+	fl     SortingFlags
+	check  bool
+	exp    string
+	err    string
+}{
+	{name: "test1", inputs: []string{"a\nc\ne\n", "b\nd\n"}, exp: "a\nb\nc\nd\ne\n"},
+	{name: "test2", inputs: []string{"", "b\n", ""}, exp: "b\n"},
+	{name: "test3", inputs: []string{"x 2\ny 10\n", "z 1\nw 3\n"}, keys: []string{"2,2n"}, exp: "z 1\nx 2\nw 3\ny 10\n"},
+	{name: "test4", inputs: []string{"c\nb\n", "a\n"}, fl: SortingFlags{reverse: true}, exp: "c\nb\na\n"},
+	{name: "test5", inputs: []string{"a\nb\n", "a\nb\n"}, fl: SortingFlags{unique: true}, exp: "a\nb\n"},
+	{name: "test6", inputs: []string{"a 1\n", "a 2\n"}, keys: []string{"1,1"}, exp: "a 1\na 2\n"},
+	{name: "test7", inputs: []string{"b\na\n", "c\n"}, exp: "b\na\nc\n"},
+	{name: "test8", inputs: []string{"a\nc\n", "d\nb\n"}, check: true, err: "in2:2: input is not sorted: b"},
+	{name: "test9", inputs: []string{"a\na\nb\n", "a\n"}, check: true, exp: "a\na\na\nb\n"},
+	{name: "test10", inputs: []string{"x 1\ny 2\n", "z 1\n"}, keys: []string{"2,2n"}, fl: SortingFlags{unique: true}, exp: "x 1\ny 2\n"},
+	{name: "test11", inputs: []string{"a\nb\n", "A\n"}, fl: SortingFlags{unique: true, count: true}, exp: "      2 a\n      1 b\n"},
+}
+
+func TestMergeSorted(t *testing.T) {
+	for _, test := range mergeSortedTests {
+		t.Run(test.name, func(t *testing.T) {
+			fl := test.fl
+			for _, spec := range test.keys {
+				require.NoError(t, (*keyList)(&fl.keys).Set(spec))
+			}
+			inputs := make([]io.Reader, len(test.inputs))
+			names := make([]string, len(test.inputs))
+			for i, s := range test.inputs {
+				inputs[i] = strings.NewReader(s)
+				names[i] = "in" + string(rune('1'+i))
+			}
+			var out bytes.Buffer
+			err := mergeSorted(inputs, names, &out, &fl, test.check)
+			if test.err != "" {
+				require.ErrorIs(t, err, ErrUnsorted)
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.exp, out.String())
+		})
+	}
+}
+
+func TestMergeSortedShards(t *testing.T) {
+	lines := randomLines(3000, 4)
+	fl := SortingFlags{}
+	for _, spec := range []string{"3,3", "2,2nr"} {
+		require.NoError(t, (*keyList)(&fl.keys).Set(spec))
+	}
+	expected := string(sortFile(append([]string(nil), lines...), &fl))
+
+	var inputs []io.Reader
+	var names []string
+	for i := 0; i < len(lines); i += 700 {
+		shard := sortLines(append([]string(nil), lines[i:min(i+700, len(lines))]...), &fl)
+		inputs = append(inputs, strings.NewReader(strings.Join(shard, "\n")+"\n"))
+		names = append(names, "-")
+	}
+	var out bytes.Buffer
+	require.NoError(t, mergeSorted(inputs, names, &out, &fl, true))
+	require.Equal(t, expected, out.String())
+}
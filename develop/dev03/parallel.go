@@ -0,0 +1,123 @@
+package main
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// minChunk - наименьшее число строк на поток: меньшие порции сортируются в одном потоке.
+const minChunk = 1 << 12
+
+// workers - число потоков сортировки для n строк.
+func (fl *SortingFlags) workers(n int) int {
+	w := fl.parallel
+	if w <= 0 {
+		w = runtime.NumCPU()
+	}
+	if w > n/minChunk {
+		w = n / minChunk
+	}
+	if w < 1 {
+		w = 1
+	}
+	return w
+}
+
+// bounds - границы порций для параллельной обработки n строк: порция i - [bounds[i], bounds[i+1]).
+func (fl *SortingFlags) bounds(n int) []int {
+	workers := fl.workers(n)
+	bounds := make([]int, workers+1)
+	for i := range bounds {
+		bounds[i] = n * i / workers
+	}
+	return bounds
+}
+
+// sortLines - устойчивая сортировка строк по ключам из флагов. Ключи каждой строки разбираются
+// один раз. При нескольких потоках строки разбираются параллельно по порциям, затем сортируются
+// в sortParsed.
+func sortLines(msg []string, fl *SortingFlags) []string {
+	lines := make([]parsedLine, len(msg))
+	bounds := fl.bounds(len(msg))
+	var wg sync.WaitGroup
+	for i := 0; i+1 < len(bounds); i++ {
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			for j := lo; j < hi; j++ {
+				lines[j] = fl.parse(msg[j])
+			}
+		}(bounds[i], bounds[i+1])
+	}
+	wg.Wait()
+
+	for i, pl := range sortParsed(lines, fl) {
+		msg[i] = pl.line
+	}
+	return msg
+}
+
+// sortParsed - устойчивая сортировка разобранных строк. При нескольких потоках строки делятся
+// на порции, которые сортируются параллельно, а затем попарно сливаются; при равных ключах
+// сохраняется исходный порядок строк. Сортируются указатели: перестановка указателя дешевле
+// перестановки разобранной строки.
+func sortParsed(lines []parsedLine, fl *SortingFlags) []*parsedLine {
+	parsed := make([]*parsedLine, len(lines))
+	for i := range lines {
+		parsed[i] = &lines[i]
+	}
+	bounds := fl.bounds(len(lines))
+	var wg sync.WaitGroup
+	for i := 0; i+1 < len(bounds); i++ {
+		wg.Add(1)
+		go func(chunk []*parsedLine) {
+			defer wg.Done()
+			sort.SliceStable(chunk, func(a, b int) bool { return fl.compareParsed(chunk[a], chunk[b]) < 0 })
+		}(parsed[bounds[i]:bounds[i+1]])
+	}
+	wg.Wait()
+
+	// Слияние соседних порций, пока не останется одна.
+	var buf []*parsedLine
+	if len(bounds) > 2 {
+		buf = make([]*parsedLine, len(parsed))
+	}
+	for len(bounds) > 2 {
+		next := []int{0}
+		for i := 0; i+1 < len(bounds); i += 2 {
+			if i+2 >= len(bounds) {
+				copy(buf[bounds[i]:], parsed[bounds[i]:bounds[i+1]])
+				next = append(next, bounds[i+1])
+				continue
+			}
+			wg.Add(1)
+			go func(lo, mid, hi int) {
+				defer wg.Done()
+				fl.mergeParsed(buf[lo:hi], parsed[lo:mid], parsed[mid:hi])
+			}(bounds[i], bounds[i+1], bounds[i+2])
+			next = append(next, bounds[i+2])
+		}
+		wg.Wait()
+		parsed, buf = buf, parsed
+		bounds = next
+	}
+	return parsed
+}
+
+// mergeParsed - сливает отсортированные a и b в dst. При равных строках первой идёт строка из a.
+func (fl *SortingFlags) mergeParsed(dst, a, b []*parsedLine) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if fl.compareParsed(b[j], a[i]) < 0 {
+			dst[k] = b[j]
+			j++
+		} else {
+			dst[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], a[i:])
+	copy(dst[k:], b[j:])
+}
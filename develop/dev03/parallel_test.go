@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var workersTests = []struct {
+	name     string
+	parallel int
+	n        int
+	exp      int
+}{
+	{name: "test1", parallel: 1, n: 1 << 20, exp: 1},
+	{name: "test2", parallel: 4, n: 1 << 20, exp: 4},
+	{name: "test3", parallel: 4, n: 2 * minChunk, exp: 2},
+	{name: "test4", parallel: 8, n: 10, exp: 1},
+	{name: "test5", parallel: 0, n: 1 << 30, exp: runtime.NumCPU()},
+}
+
+func TestWorkers(t *testing.T) {
+	for _, test := range workersTests {
+		t.Run(test.name, func(t *testing.T) {
+			fl := SortingFlags{parallel: test.parallel}
+			require.Equal(t, test.exp, fl.workers(test.n))
+		})
+	}
+}
+
+var parallelTests = []struct {
+	name string
+	keys []string
+	fl   SortingFlags
+}{
+	{name: "test1"},
+	{name: "test2", keys: []string{"2,2n"}},
+	{name: "test3", keys: []string{"2,2n", "1,1r"}},
+	{name: "test4", keys: []string{"3,3"}, fl: SortingFlags{reverse: true}},
+	{name: "test5", keys: []string{"1.2,1.3"}, fl: SortingFlags{blanks: true}},
+}
+
+func TestSortLinesParallel(t *testing.T) {
+	lines := randomLines(5*minChunk+17, 2)
+	for _, test := range parallelTests {
+		t.Run(test.name, func(t *testing.T) {
+			fl := test.fl
+			for _, spec := range test.keys {
+				require.NoError(t, (*keyList)(&fl.keys).Set(spec))
+			}
+			expected := append([]string(nil), lines...)
+			sort.SliceStable(expected, func(i, j int) bool { return fl.compare(expected[i], expected[j]) < 0 })
+
+			for _, parallel := range []int{1, 2, 3, 5, 8} {
+				fl.parallel = parallel
+				actual := sortLines(append([]string(nil), lines...), &fl)
+				require.Equal(t, expected, actual, fmt.Sprintf("parallel=%d", parallel))
+			}
+		})
+	}
+}
@@ -20,172 +20,175 @@ package main
 // -h — сортировать по числовому значению с учетом суффиксов
 
 import (
-	"bufio"
-	"flag"
-	"fmt"
-	"io/fs"
-	"io/ioutil"
-	"log"
+	"bytes"
+	"io"
 	"os"
-	"sort"
-	"strconv"
 	"strings"
 )
 
-// Структура, хранящая в себе флаги.
+// Структура, хранящая в себе флаги. Глобальные модификаторы num, reverse, month, human, version и blanks
+// применяются к ключам без собственных модификаторов и ко всей строке, если ключи не заданы.
 type SortingFlags struct {
-	column  int
-	num     bool
-	reverse bool
-	unique  bool
+	keys     []sortKey
+	num      bool
+	reverse  bool
+	month    bool
+	human    bool
+	version  bool
+	blanks   bool
+	unique   bool
+	count    bool      // при unique выводить перед строкой число её повторов, как uniq -c
+	collate  *collator // правила сопоставления текста, nil - побайтово без учёта регистра
+	sep      string    // разделитель полей, пустая строка - серии пробелов и табуляций
+	parallel int       // число потоков сортировки, 0 - по числу ядер
+	bufSize  int64     // размер буфера внешней сортировки в байтах, 0 - сортировка в памяти
+	tempDir  string    // каталог временных файлов внешней сортировки
 }
 
-// fileRead - функция построкового чтения из файла.
-func fileRead(buf *bufio.Scanner) []string {
-	s := make([]string, 0)
+// wholeLine - ключ сортировки по всей строке.
+var wholeLine = []sortKey{{startField: 1}}
 
-	for buf.Scan() {
-		s = append(s, buf.Text())
-	}
-	return s
+// global - глобальные модификаторы сравнения.
+func (fl *SortingFlags) global() keyOpts {
+	return keyOpts{num: fl.num, reverse: fl.reverse, month: fl.month, human: fl.human, version: fl.version, blanks: fl.blanks}
 }
 
-// standartSort - функция для начальной сортировки строк.
-func standartSort(msg []string) []string {
-	sort.SliceStable(msg, func(i, j int) bool { return strings.ToLower(msg[i]) < strings.ToLower(msg[j]) })
-	return msg
+// activeKeys - ключи сортировки, если ключи не заданы - вся строка.
+func (fl *SortingFlags) activeKeys() []sortKey {
+	if len(fl.keys) == 0 {
+		return wholeLine
+	}
+	return fl.keys
 }
 
-// uniqueSort - функция удаляет из слайса строк дубликаты.
-func uniqueSort(msg []string) []string {
-	s := make(map[string]bool)
-	ret := make([]string, 0)
-	for i := 0; i < len(msg); i++ {
-		if !s[msg[i]] {
-			s[msg[i]] = true
-			ret = append(ret, msg[i])
-		}
+// keyOpts - модификаторы ключа k: собственные или, если их нет, глобальные.
+func (fl *SortingFlags) keyOpts(k sortKey) keyOpts {
+	if k.keyOpts.any() {
+		return k.keyOpts
 	}
-	return ret
+	return fl.global()
 }
 
-// reverseSort - функция переворачивает полученный слайс строк.
-func reverseSort(msg []string) []string {
-	for i, j := 0, len(msg)-1; i < j; i, j = i+1, j-1 {
-		msg[i], msg[j] = msg[j], msg[i]
+// parsedLine - строка с разобранными значениями ключей: строка разбивается на поля один раз,
+// а не при каждом сравнении.
+type parsedLine struct {
+	line  string
+	whole string // строка в нижнем регистре или её ключ сопоставления для сравнения при равенстве ключей
+	keys  []keyValue
+}
+
+// parse - разбирает ключи строки line.
+func (fl *SortingFlags) parse(line string) parsedLine {
+	keys := fl.activeKeys()
+	pl := parsedLine{line: line, keys: make([]keyValue, len(keys))}
+	if fl.collate != nil {
+		pl.whole = fl.collate.key(line)
+	} else {
+		pl.whole = strings.ToLower(line)
+	}
+	for i, k := range keys {
+		pl.keys[i] = parseKeyValue(k.extract(line, fl.sep), fl.keyOpts(k), fl.collate)
 	}
-	return msg
+	return pl
 }
 
-// columnSort - функция сортировки по заданной колонке. Сортирует как по числам, так и по символам.
-func columnSort(msg []string, fl *SortingFlags) []string {
-	s := make([][]string, 0)
+// compare - сравнивает строки по ключам сортировки по порядку: следующий ключ сравнивается,
+// только если предыдущие равны. При равенстве всех ключей строки сравниваются целиком
+// без учёта регистра или по правилам сопоставления (в обратном порядке при -r). Возвращает -1, 0 или 1.
+func (fl *SortingFlags) compare(a, b string) int {
+	pa, pb := fl.parse(a), fl.parse(b)
+	return fl.compareParsed(&pa, &pb)
+}
 
-	k := fl.column - 1
-	if k < 0 {
-		k = 0
+// compareParsed - compare для разобранных строк. При unique строки с равными ключами равны:
+// сравнение целых строк не выполняется, как в sort -u.
+func (fl *SortingFlags) compareParsed(a, b *parsedLine) int {
+	if c := fl.compareKeys(a, b); c != 0 || fl.unique {
+		return c
 	}
-
-	for _, str := range msg {
-		s = append(s, strings.Split(str, " "))
+	c := strings.Compare(a.whole, b.whole)
+	if fl.reverse {
+		return -c
 	}
+	return c
+}
 
-	if fl.num {
-		sort.SliceStable(s, func(i, j int) bool {
-			if len(s[i]) > k && len(s[j]) > k {
-				x, err := strconv.Atoi(s[i][k])
-				if err != nil {
-					fmt.Println(err)
-					return false
-				}
-				y, err := strconv.Atoi(s[j][k])
-				if err != nil {
-					fmt.Println(err)
-					return false
-				}
-				return x < y
+// compareKeys - сравнивает разобранные строки только по ключам сортировки.
+func (fl *SortingFlags) compareKeys(a, b *parsedLine) int {
+	for i, k := range fl.activeKeys() {
+		opts := fl.keyOpts(k)
+		if c := compareValues(a.keys[i], b.keys[i], opts); c != 0 {
+			if opts.reverse {
+				return -c
 			}
-			return false
-		})
-	} else {
-		sort.SliceStable(s, func(i, j int) bool {
-			if len(s[i]) > k && len(s[j]) > k {
-				return strings.ToLower(s[i][k]) < strings.ToLower(s[j][k])
-			}
-			return false
-		})
+			return c
+		}
 	}
+	return 0
+}
 
-	ret := make([]string, 0)
-	// строка файла которая была разделена пробелом, джониться обратно c пробелом
-	for i := 0; i < len(s); i++ {
-		str := strings.Join(s[i], " ")
-		ret = append(ret, str)
+// fileRead - функция построкового чтения из файла. Длина строки не ограничена.
+func fileRead(r io.Reader) ([]string, error) {
+	s := make([]string, 0)
+	lr := newLineReader(r)
+	for {
+		line, err := lr.next()
+		if err == io.EOF {
+			return s, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, line)
 	}
+}
 
-	// возвращаем отсортированный слайс
+// uniqueLines - убирает из отсортированных строк строки, равные предыдущей по ключам сортировки.
+// Из каждой группы равных строк остаётся первая.
+func uniqueLines(msg []string, fl *SortingFlags) []string {
+	ret := msg[:0]
+	var last parsedLine
+	for i, line := range msg {
+		pl := fl.parse(line)
+		if i > 0 && fl.compareKeys(&last, &pl) == 0 {
+			continue
+		}
+		ret = append(ret, line)
+		last = pl
+	}
 	return ret
 }
 
-// sortFile - основная функция сортировки. Последовательно вызывает необходимые функции в зависимости от флагов.
+// sortFile - основная функция сортировки: сортирует строки по ключам из флагов и, если нужно,
+// убирает строки, равные предыдущей по ключам.
 func sortFile(msg []string, fl *SortingFlags) []byte {
-	msg = standartSort(msg)
-
-	if fl.unique {
-		msg = uniqueSort(msg)
-	}
-
-	if fl.column > -1 {
-		msg = columnSort(msg, fl)
-	}
-
-	if fl.reverse {
-		msg = reverseSort(msg)
-	}
-
-	return []byte(strings.Join(msg, "\n"))
+	var b bytes.Buffer
+	writeLines(&b, sortLines(msg, fl), fl)
+	return b.Bytes()
 }
 
-// concat - функция конкатенации 2 строк
-func concat(x, y string) string {
-	var builder strings.Builder
-	builder.Grow(len(x) + len(y)) // Эта строка выделяет память
-	builder.WriteString(x)        //Записывает в builder строку.
-	builder.WriteString(y)
-	return builder.String()
+// checkSorted - проверяет, отсортированы ли строки r по ключам из флагов. Возвращает номер (с 1)
+// и текст первой строки, которая меньше предыдущей (или равна ей по ключам при unique), либо 0, если порядок верный.
+func checkSorted(r io.Reader, fl *SortingFlags) (int, string, error) {
+	lr := newLineReader(r)
+	var prev string
+	for n := 1; ; n++ {
+		line, err := lr.next()
+		if err == io.EOF {
+			return 0, "", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		if n > 1 {
+			if c := fl.compare(prev, line); c > 0 || fl.unique && c == 0 {
+				return n, line, nil
+			}
+		}
+		prev = line
+	}
 }
 
-var (
-	column  int
-	num     bool
-	reverse bool
-	unique  bool
-)
-
 func main() {
-	flag.IntVar(&column, "k", -1, "колонка для сортировки")
-	flag.BoolVar(&num, "n", false, "сортировать по числовому значению")
-	flag.BoolVar(&reverse, "r", false, "сортировать в обратном порядке")
-	flag.BoolVar(&unique, "u", false, "убрать повторяющиеся значения")
-	flag.Parse()
-
-	fl := &SortingFlags{unique: unique, column: column, reverse: reverse, num: num}
-	// fl := &SortingFlags{unique: true, column: 2, reverse: true, num: true}
-	filename := flag.Arg(0)
-	// filename := "text.txt"
-	if filename == "" {
-		fmt.Println("error: enter the file name")
-		os.Exit(1)
-	}
-	f, err := os.Open(filename)
-	defer f.Close()
-	if err != nil {
-		log.Fatalln(err)
-	}
-	buf := bufio.NewScanner(f)
-	msg := fileRead(buf)
-	err = ioutil.WriteFile(concat("Sorted", f.Name()), sortFile(msg, fl), fs.ModePerm)
-	if err != nil {
-		log.Fatalln(err)
-	}
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
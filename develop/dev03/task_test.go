@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -12,7 +13,7 @@ import (
 
 type tableTest struct {
 	name    string
-	column  int
+	keys    []string
 	reverse bool
 	unique  bool
 	num     bool
@@ -23,7 +24,7 @@ type tableTest struct {
 var tableTests = []tableTest{
 	{
 		name:    "test1",
-		column:  2,
+		keys:    []string{"2"},
 		reverse: true,
 		unique:  true,
 		num:     true,
@@ -38,7 +39,7 @@ var tableTests = []tableTest{
 	},
 	{
 		name:    "test2",
-		column:  9,
+		keys:    []string{"9"},
 		reverse: true,
 		unique:  true,
 		num:     false,
@@ -51,16 +52,43 @@ var tableTests = []tableTest{
 			"drwxrwxr-x 2 konstantin konstantin 4096 ноя 17 02:45 .vscode",
 		},
 	},
+	{
+		name: "test3",
+		keys: []string{"6,6M", "7,7n", "8"},
+		file: "text.txt",
+		exp: []string{
+			"drwxrwxr-x 2 konstantin konstantin 4096 ноя 17 02:45 .vscode",
+			"drwxrwxr-x 4 konstantin konstantin 4096 ноя 18 07:54 l0",
+			"-rw-rw-r-- 1 konstantin konstantin 1347 ноя 18 09:50 psql_commands_l0",
+			"drwxrwxr-x 9 konstantin konstantin 4096 ноя 28 11:55 l1",
+			"drwxrwxr-x 6 konstantin konstantin 4096 дек 23 05:21 l2",
+		},
+	},
+	{
+		name:    "test4",
+		keys:    []string{"5,5n", "6,6Mr"},
+		reverse: true,
+		file:    "text.txt",
+		exp: []string{
+			"-rw-rw-r-- 1 konstantin konstantin 1347 ноя 18 09:50 psql_commands_l0",
+			"drwxrwxr-x 6 konstantin konstantin 4096 дек 23 05:21 l2",
+			"drwxrwxr-x 9 konstantin konstantin 4096 ноя 28 11:55 l1",
+			"drwxrwxr-x 4 konstantin konstantin 4096 ноя 18 07:54 l0",
+			"drwxrwxr-x 2 konstantin konstantin 4096 ноя 17 02:45 .vscode",
+		},
+	},
 }
 
 func TestSortFile(t *testing.T) {
 	for _, test := range tableTests {
 		fl := &SortingFlags{
-			column:  test.column,
 			reverse: test.reverse,
 			unique:  test.unique,
 			num:     test.num,
 		}
+		for _, spec := range test.keys {
+			require.NoError(t, (*keyList)(&fl.keys).Set(spec))
+		}
 		f, err := os.Open(test.file)
 		if err != nil {
 			log.Fatalln(err)
@@ -72,8 +100,88 @@ func TestSortFile(t *testing.T) {
 			s = append(s, buf.Text())
 		}
 		t.Run(test.name, func(t *testing.T) {
-			res := strings.Split(string(sortFile(s, fl)), "\n")
+			res := strings.Split(strings.TrimSuffix(string(sortFile(s, fl)), "\n"), "\n")
 			require.Equal(t, test.exp, res)
 		})
 	}
 }
+
+var checkSortedTests = []struct {
+	name string
+	in   string
+	fl   SortingFlags
+	line int
+	text string
+}{
+	{name: "test1", in: "a\nb\nc\n", line: 0},
+	{name: "test2", in: "a\nc\nb\n", line: 3, text: "b"},
+	{name: "test3", in: "10\n9\n", fl: SortingFlags{num: true}, line: 2, text: "9"},
+	{name: "test4", in: "10\n9\n", line: 0},
+	{name: "test5", in: "a\na\n", line: 0},
+	{name: "test6", in: "a\na\n", fl: SortingFlags{unique: true}, line: 2, text: "a"},
+	{name: "test7", in: "янв\nдек\nноя\n", fl: SortingFlags{month: true, reverse: true}, line: 2, text: "дек"},
+	{name: "test8", in: "", line: 0},
+	{name: "test9", in: "A\na\n", fl: SortingFlags{unique: true}, line: 2, text: "a"},
+	{name: "test10", in: "x 1\ny 01\n", fl: SortingFlags{unique: true, keys: []sortKey{{startField: 2, endField: 2, keyOpts: keyOpts{num: true}}}}, line: 2, text: "y 01"},
+}
+
+func TestCheckSorted(t *testing.T) {
+	for _, test := range checkSortedTests {
+		t.Run(test.name, func(t *testing.T) {
+			line, text, err := checkSorted(strings.NewReader(test.in), &test.fl)
+			require.NoError(t, err)
+			require.Equal(t, test.line, line)
+			require.Equal(t, test.text, text)
+		})
+	}
+}
+
+var uniqueTests = []struct {
+	name string
+	keys []string
+	fl   SortingFlags
+	in   []string
+	exp  string
+}{
+	{name: "test1", keys: []string{"2"}, in: []string{"b 1", "a 1", "c 2"}, exp: "b 1\nc 2\n"},
+	{name: "test2", in: []string{"A", "b", "a"}, exp: "A\nb\n"},
+	{name: "test3", fl: SortingFlags{num: true}, in: []string{"01", "2", "1"}, exp: "01\n2\n"},
+	{name: "test4", fl: SortingFlags{reverse: true}, in: []string{"a", "b", "A"}, exp: "b\na\n"},
+	{name: "test5", keys: []string{"2,2n"}, in: []string{"x 2", "y 1", "z 2"}, exp: "y 1\nx 2\n"},
+	{name: "test6", fl: SortingFlags{count: true}, in: []string{"b", "a", "B", "a", "a"}, exp: "      3 a\n      2 b\n"},
+	{name: "test7", keys: []string{"1,1"}, fl: SortingFlags{count: true}, in: []string{"x 1", "y 2", "x 3"}, exp: "      2 x 1\n      1 y 2\n"},
+	{name: "test8", fl: SortingFlags{count: true}, in: []string{}, exp: ""},
+	{name: "test9", keys: []string{"1,1n"}, in: []string{"18446744073709551617 b", "18446744073709551616 a", "018446744073709551616 c"}, exp: "18446744073709551616 a\n18446744073709551617 b\n"},
+	{name: "test10", fl: SortingFlags{num: true, count: true}, in: []string{"9007199254740993", "9007199254740992"}, exp: "      1 9007199254740992\n      1 9007199254740993\n"},
+}
+
+func TestSortFileUnique(t *testing.T) {
+	for _, test := range uniqueTests {
+		t.Run(test.name, func(t *testing.T) {
+			fl := test.fl
+			fl.unique = true
+			for _, spec := range test.keys {
+				require.NoError(t, (*keyList)(&fl.keys).Set(spec))
+			}
+			require.Equal(t, test.exp, string(sortFile(append([]string(nil), test.in...), &fl)))
+		})
+	}
+}
+
+func BenchmarkSortLines(b *testing.B) {
+	lines := randomLines(200000, 3)
+	fl := SortingFlags{}
+	for _, spec := range []string{"2,2n", "1,1"} {
+		require.NoError(b, (*keyList)(&fl.keys).Set(spec))
+	}
+	for _, parallel := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("parallel=%d", parallel), func(b *testing.B) {
+			fl.parallel = parallel
+			buf := make([]string, len(lines))
+			for i := 0; i < b.N; i++ {
+				copy(buf, lines)
+				sortLines(buf, &fl)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// compareVersion - естественное сравнение строк для -V, как сравнение версий в dpkg и sort -V:
+// строки делятся на чередующиеся нецифровые и цифровые части, цифровые части сравниваются
+// как числа без учёта ведущих нулей, нецифровые - посимвольно, при этом ~ идёт раньше всего,
+// даже конца строки, а буквы - раньше остальных символов. Поэтому file2 < file10 и 1.0~rc1 < 1.0.
+// Возвращает -1, 0 или 1.
+func compareVersion(a, b string) int {
+	for a != "" || b != "" {
+		for a != "" && !isDigit(a[0]) || b != "" && !isDigit(b[0]) {
+			ra, na := versionOrder(a)
+			rb, nb := versionOrder(b)
+			if ra != rb {
+				return compareInt(ra, rb)
+			}
+			a, b = a[na:], b[nb:]
+		}
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != db {
+			return compareInt(da, db)
+		}
+		if c := strings.Compare(a[:da], b[:db]); c != 0 {
+			return c
+		}
+		a, b = a[da:], b[db:]
+	}
+	return 0
+}
+
+// versionOrder - вес первого символа s при сравнении нецифровых частей версии и его длина в байтах.
+// Цифра и конец строки весят 0 и не сдвигают позицию.
+func versionOrder(s string) (int, int) {
+	if s == "" || isDigit(s[0]) {
+		return 0, 0
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	switch {
+	case r == '~':
+		return -1, n
+	case unicode.IsLetter(r):
+		return int(r), n
+	}
+	return int(r) + unicode.MaxRune + 1, n
+}
+
+// digitPrefix - длина цифрового префикса s.
+func digitPrefix(s string) int {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// isDigit - является ли байт десятичной цифрой.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
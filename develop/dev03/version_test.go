@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var compareVersionTests = []struct {
+	name     string
+	a, b     string
+	expected int
+}{
+	{name: "test1", a: "file2", b: "file10", expected: -1},
+	{name: "test2", a: "file10", b: "file2", expected: 1},
+	{name: "test3", a: "1.2.10", b: "1.2.9", expected: 1},
+	{name: "test4", a: "1.0~rc1", b: "1.0", expected: -1},
+	{name: "test5", a: "1.0", b: "1.0a", expected: -1},
+	{name: "test6", a: "a01b", b: "a1b", expected: 0},
+	{name: "test7", a: "1a", b: "1+", expected: -1},
+	{name: "test8", a: "v1-x", b: "v1.x", expected: -1},
+	{name: "test9", a: "", b: "0", expected: 0},
+	{name: "test10", a: "том2", b: "том10", expected: -1},
+	{name: "test11", a: "abc", b: "abd", expected: -1},
+	{name: "test12", a: "", b: "~", expected: 1},
+}
+
+func TestCompareVersion(t *testing.T) {
+	for _, test := range compareVersionTests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, compareVersion(test.a, test.b))
+			require.Equal(t, -test.expected, compareVersion(test.b, test.a))
+		})
+	}
+}